@@ -4,35 +4,787 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"iter"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/PatrickCronin/routesum/pkg/routesum"
+	"github.com/PatrickCronin/routesum/pkg/routesum/format"
 )
 
+// stdinName names standard input in explanations.
+const stdinName = "stdin"
+
+// Ways of handling input that isn't understood.
+const (
+	onErrorAbort  = "abort"
+	onErrorSkip   = "skip"
+	onErrorReport = "report"
+)
+
+// Output formats for summaries.
+const (
+	outputFormatCIDR  = "cidr"
+	outputFormatRange = "range"
+	outputFormatNFT   = "nft"
+	outputFormatIPSet = "ipset"
+	outputFormatCisco = "cisco"
+	outputFormatJunos = "junos"
+	outputFormatBIRD  = "bird"
+	outputFormatFRR   = "frr"
+
+	outputFormatAWSPrefixList    = "aws-prefix-list"
+	outputFormatAWSSecurityGroup = "aws-security-group"
+	outputFormatGCPFirewall      = "gcp-firewall"
+	outputFormatAzureNSG         = "azure-nsg"
+
+	outputFormatK8sNetworkPolicy = "k8s-networkpolicy"
+	outputFormatCalicoNetworkSet = "calico-networkset"
+)
+
+var (
+	errInvalidFlagValue = errors.New("invalid flag value")
+	errIncompatibleArgs = errors.New("incompatible arguments")
+	errInvalidArgs      = errors.New("invalid arguments")
+)
+
+// options holds the settings given on the command line.
+type options struct {
+	descending        bool
+	explain           bool
+	json              bool
+	maxEntries        int
+	maxOvercoverRatio float64
+	outputFormat      string
+	name              string
+	nftTable          string
+	ipsetMaxElem      int
+	ipv4Bounds        format.Bounds
+	ipv6Bounds        format.Bounds
+	chunkSize         int
+	namespace         string
+	labels            map[string]string
+	exclude           string
+	strict            bool
+	warn              bool
+	unmap             []routesum.Embedding
+	embedIPv4         routesum.Embedding
+	onError           string
+	inputs            []string
+}
+
 func main() {
-	if err := summarize(os.Stdin, os.Stdout); err != nil {
-		fmt.Fprintf(os.Stderr, "summarize: %s\n", err.Error())
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
 	}
 }
 
-func summarize(in io.Reader, out io.Writer) error {
-	rs := routesum.NewRouteSum()
+// run dispatches to the mode named by the first of args, if any, and otherwise summarizes the input. Flags may follow
+// the mode, and input files may follow the flags; without any, in is read.
+func run(args []string, in io.Reader, out, errOut io.Writer) error {
+	mode := ""
+	if len(args) > 0 && slices.Contains([]string{"complement", "stats"}, args[0]) {
+		mode = args[0]
+		args = args[1:]
+	}
+
+	opts, err := parseFlags(args, errOut)
+	if err != nil {
+		return err
+	}
+
+	if opts.explain && mode != "" {
+		return fmt.Errorf("%w: --explain can't be used with %s", errIncompatibleArgs, mode)
+	}
+	if opts.json && mode != "stats" {
+		return fmt.Errorf("%w: --json can only be used with stats", errIncompatibleArgs)
+	}
+	if opts.outputFormat != outputFormatCIDR && (opts.explain || mode == "stats") {
+		return fmt.Errorf("%w: --output-format can't be used with --explain or stats", errIncompatibleArgs)
+	}
+	if opts.exclude != "" &&
+		opts.outputFormat != outputFormatK8sNetworkPolicy &&
+		opts.outputFormat != outputFormatCalicoNetworkSet {
+		return fmt.Errorf(
+			"%w: --exclude can't be used with --output-format %s",
+			errIncompatibleArgs,
+			opts.outputFormat,
+		)
+	}
+	if newFormat(opts, nil) != nil && opts.descending {
+		return fmt.Errorf(
+			"%w: --descending can't be used with --output-format %s",
+			errIncompatibleArgs,
+			opts.outputFormat,
+		)
+	}
+
+	switch mode {
+	case "":
+		if err := summarize(in, out, errOut, opts); err != nil {
+			return fmt.Errorf("summarize: %w", err)
+		}
+	case "complement":
+		if err := complement(in, out, errOut, opts); err != nil {
+			return fmt.Errorf("complement: %w", err)
+		}
+	case "stats":
+		if err := stats(in, out, errOut, opts); err != nil {
+			return fmt.Errorf("stats: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// defaultOptions returns the settings used when no flags are given.
+func defaultOptions() options {
+	return options{
+		descending:        false,
+		explain:           false,
+		json:              false,
+		maxEntries:        0,
+		maxOvercoverRatio: 0,
+		outputFormat:      outputFormatCIDR,
+		name:              "routesum",
+		nftTable:          "inet filter",
+		ipsetMaxElem:      65536,
+		ipv4Bounds:        format.Bounds{GE: 0, LE: 0},
+		ipv6Bounds:        format.Bounds{GE: 0, LE: 0},
+		chunkSize:         0,
+		namespace:         "",
+		labels:            nil,
+		exclude:           "",
+		strict:            false,
+		warn:              false,
+		unmap:             nil,
+		embedIPv4:         0,
+		onError:           onErrorAbort,
+		inputs:            nil,
+	}
+}
+
+func parseFlags(args []string, errOut io.Writer) (options, error) {
+	opts := defaultOptions()
+
+	flags := flag.NewFlagSet("routesum", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: routesum [complement|stats] [flags] [file|directory|-]...\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	var unmapNames, embedIPv4Name string
+	var labels []string
+	addInputFlags(flags, &opts, &unmapNames)
+	addOutputFlags(flags, &opts, &embedIPv4Name)
+	addFormatFlags(flags, &opts, &labels)
+
+	if err := flags.Parse(args); err != nil {
+		return opts, fmt.Errorf("parse flags: %w", err)
+	}
+	opts.inputs = flags.Args()
+
+	if unmapNames != "" {
+		for name := range strings.SplitSeq(unmapNames, ",") {
+			e, err := parseEmbedding(name)
+			if err != nil {
+				return opts, err
+			}
+			opts.unmap = append(opts.unmap, e)
+		}
+	}
+	if embedIPv4Name != "" {
+		e, err := parseEmbedding(embedIPv4Name)
+		if err != nil {
+			return opts, err
+		}
+		opts.embedIPv4 = e
+	}
+	parsedLabels, err := parseLabels(labels)
+	if err != nil {
+		return opts, err
+	}
+	opts.labels = parsedLabels
+
+	return opts, validateOptions(opts)
+}
+
+// addInputFlags adds the flags controlling how input is read.
+func addInputFlags(flags *flag.FlagSet, opts *options, unmapNames *string) {
+	flags.BoolVar(&opts.strict, "strict", false, "fail on networks with host bits set, such as 192.0.2.5/24")
+	flags.BoolVar(
+		&opts.warn,
+		"warn",
+		false,
+		"warn about networks with host bits set, such as 192.0.2.5/24, and summarize the networks they're in",
+	)
+	flags.StringVar(
+		unmapNames,
+		"unmap",
+		"",
+		"treat IPv6 addresses embedding IPv4 ones in the comma-separated `WAYS` as those IPv4 addresses: mapped "+
+			"(::ffff:0:0/96), compatible (::/96) or nat64 (64:ff9b::/96)",
+	)
+	flags.StringVar(
+		&opts.onError,
+		"on-error",
+		onErrorAbort,
+		"on input that isn't understood, `ACTION`: abort, skip the line, or report the line to STDERR and skip it",
+	)
+}
+
+// addOutputFlags adds the flags controlling what is output, and how.
+func addOutputFlags(flags *flag.FlagSet, opts *options, embedIPv4Name *string) {
+	flags.BoolVar(&opts.descending, "descending", false, "output in descending rather than ascending order")
+	flags.BoolVar(
+		&opts.explain,
+		"explain",
+		false,
+		"instead of the summary, output the input lines that contributed to each summarized network, and those that "+
+			"were already covered",
+	)
+	flags.BoolVar(&opts.json, "json", false, "output stats as JSON rather than text")
+	flags.IntVar(
+		&opts.maxEntries,
+		"max-entries",
+		0,
+		"merge networks, covering addresses not in the input, until at most `N` entries are output (0 for no limit)",
+	)
+	flags.Float64Var(
+		&opts.maxOvercoverRatio,
+		"max-overcover-ratio",
+		0,
+		"merge networks wherever the addresses not in the input make up at most `R` (0 to 1) of the merged network",
+	)
+	flags.StringVar(
+		&opts.outputFormat,
+		"output-format",
+		outputFormatCIDR,
+		"output the summary as `FORMAT`: cidr for IPs and networks, range for ranges of IPs such as "+
+			"192.0.2.10-192.0.2.77, nft for an nftables script, ipset for an ipset restore script, cisco, junos, "+
+			"bird or frr for router prefix lists, or aws-prefix-list, aws-security-group, gcp-firewall or azure-nsg "+
+			"for cloud rules as JSON, or k8s-networkpolicy or calico-networkset for Kubernetes YAML",
+	)
+	flags.StringVar(&opts.name, "name", "routesum", "name the sets or lists output by --output-format `NAME`")
+	flags.StringVar(
+		embedIPv4Name,
+		"embed-ipv4",
+		"",
+		"output IPv4 networks embedded in IPv6 in `WAY`: mapped, compatible or nat64",
+	)
+}
+
+// addFormatFlags adds the flags tuning particular output formats.
+func addFormatFlags(flags *flag.FlagSet, opts *options, labels *[]string) {
+	flags.StringVar(
+		&opts.nftTable,
+		"nft-table",
+		"inet filter",
+		"put the sets output by --output-format nft in `TABLE`, given as its family and name",
+	)
+	flags.IntVar(
+		&opts.ipsetMaxElem,
+		"ipset-maxelem",
+		65536,
+		"create the sets output by --output-format ipset to hold at most `N` networks, the same N every time",
+	)
+	flags.IntVar(&opts.ipv4Bounds.GE, "ipv4-ge", 0, "match IPv4 networks of at least `LEN` bits in prefix lists")
+	flags.IntVar(&opts.ipv4Bounds.LE, "ipv4-le", 0, "match IPv4 networks of up to `LEN` bits in prefix lists")
+	flags.IntVar(&opts.ipv6Bounds.GE, "ipv6-ge", 0, "match IPv6 networks of at least `LEN` bits in prefix lists")
+	flags.IntVar(&opts.ipv6Bounds.LE, "ipv6-le", 0, "match IPv6 networks of up to `LEN` bits in prefix lists")
+	flags.IntVar(
+		&opts.chunkSize,
+		"chunk-size",
+		0,
+		"split cloud rules and lists into as many as needed to hold at most `N` networks each (0 for the "+
+			"provider's limit)",
+	)
+	flags.StringVar(
+		&opts.namespace,
+		"namespace",
+		"",
+		"put the policy output by --output-format k8s-networkpolicy in `NAMESPACE`",
+	)
+	flags.Func(
+		"label",
+		"label the network set output by --output-format calico-networkset with `KEY=VALUE` (repeatable)",
+		func(s string) error {
+			*labels = append(*labels, s)
+			return nil
+		},
+	)
+	flags.StringVar(
+		&opts.exclude,
+		"exclude",
+		"",
+		"leave the IPs and networks in `FILE` out of the summary output by --output-format k8s-networkpolicy or "+
+			"calico-networkset",
+	)
+}
+
+// validateOptions checks option values that don't depend on the mode.
+func validateOptions(opts options) error {
+	if opts.maxEntries < 0 {
+		return fmt.Errorf("%w: --max-entries must not be negative", errInvalidFlagValue)
+	}
+	if !slices.Contains(outputFormats(), opts.outputFormat) {
+		return fmt.Errorf("%w: unknown --output-format %s", errInvalidFlagValue, opts.outputFormat)
+	}
+	if opts.chunkSize < 0 {
+		return fmt.Errorf("%w: --chunk-size must not be negative", errInvalidFlagValue)
+	}
+	if len(strings.Fields(opts.nftTable)) != 2 {
+		return fmt.Errorf("%w: --nft-table must be a family and a name, such as inet filter", errInvalidFlagValue)
+	}
+	if opts.onError != onErrorAbort && opts.onError != onErrorSkip && opts.onError != onErrorReport {
+		return fmt.Errorf("%w: unknown --on-error %s", errInvalidFlagValue, opts.onError)
+	}
+	if opts.strict && opts.warn {
+		return fmt.Errorf("%w: --strict and --warn can't be used together", errIncompatibleArgs)
+	}
+
+	return nil
+}
+
+// parseLabels returns the labels given as KEY=VALUE, or nil if none are.
+func parseLabels(labels []string) (map[string]string, error) {
+	var parsed map[string]string
+	for _, label := range labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: --label must be a key and value, such as role=blocklist", errInvalidFlagValue)
+		}
+		if parsed == nil {
+			parsed = map[string]string{}
+		}
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// parseEmbedding returns the embedding of IPv4 addresses in IPv6 ones with the given name.
+func parseEmbedding(name string) (routesum.Embedding, error) {
+	for _, e := range routesum.Embeddings() {
+		if e.String() == name {
+			return e, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unknown embedding %s", errInvalidFlagValue, name)
+}
+
+func summarize(in io.Reader, out, errOut io.Writer, opts options) error {
+	rs, err := readRouteSum(in, errOut, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := reduce(rs, errOut, opts); err != nil {
+		return err
+	}
+
+	if opts.explain {
+		return writeExplanation(out, rs, opts)
+	}
+
+	return writeRouteSum(out, errOut, rs, opts)
+}
+
+// complement writes every IPv4 and IPv6 network not covered by the input.
+func complement(in io.Reader, out, errOut io.Writer, opts options) error {
+	rs, err := readRouteSum(in, errOut, opts)
+	if err != nil {
+		return err
+	}
+
+	rs = rs.Complement()
+	if err := reduce(rs, errOut, opts); err != nil {
+		return err
+	}
+
+	return writeRouteSum(out, errOut, rs, opts)
+}
+
+// stats writes statistics about the summary of the input.
+func stats(in io.Reader, out, errOut io.Writer, opts options) error {
+	rs, err := readRouteSum(in, errOut, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := reduce(rs, errOut, opts); err != nil {
+		return err
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rs.Stats()); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+
+		return nil
+	}
+
+	return writeStatsText(out, rs.Stats())
+}
+
+// reduce makes any lossy summarization requested, reporting how many addresses it added to errOut.
+func reduce(rs *routesum.RouteSum, errOut io.Writer, opts options) error {
+	if opts.maxOvercoverRatio != 0 {
+		merged, err := rs.SummarizeWithinRatio(opts.maxOvercoverRatio)
+		if err != nil {
+			return fmt.Errorf("reduce summary: %w", err)
+		}
+		for _, m := range merged {
+			fmt.Fprintf(errOut, "%s over-covers %s addresses\n", m.Prefix.String(), m.Overcovered.String())
+		}
+	}
+
+	if opts.maxEntries == 0 {
+		return nil
+	}
+
+	overcovered, err := rs.SummarizeTo(opts.maxEntries)
+	if err != nil {
+		return fmt.Errorf("reduce summary: %w", err)
+	}
+	if overcovered.Sign() > 0 {
+		fmt.Fprintf(errOut, "over-covered %s addresses to fit %d entries\n", overcovered.String(), opts.maxEntries)
+	}
+
+	return nil
+}
+
+// routeSumOptions returns the RouteSum options for whether provenance should be recorded, and how host bits and
+// embedded IPv4 addresses should be handled, warning about host bits to errOut.
+func routeSumOptions(errOut io.Writer, opts options) []routesum.Option {
+	var rsOpts []routesum.Option
+	if opts.explain {
+		rsOpts = append(rsOpts, routesum.WithProvenance())
+	}
+	if len(opts.unmap) > 0 {
+		rsOpts = append(rsOpts, routesum.WithUnmapping(opts.unmap...))
+	}
+	if opts.embedIPv4 != 0 {
+		rsOpts = append(rsOpts, routesum.WithEmbeddedOutput(opts.embedIPv4))
+	}
+	if opts.strict {
+		rsOpts = append(rsOpts, routesum.WithStrict())
+	}
+	if opts.warn {
+		rsOpts = append(rsOpts, routesum.WithHostBitsWarning(func(input routesum.Input) {
+			fmt.Fprintf(
+				errOut,
+				"%s: %s has host bits set; using %s\n",
+				input.Source.String(),
+				input.Prefix.String(),
+				input.Prefix.Masked().String(),
+			)
+		}))
+	}
+
+	return rsOpts
+}
+
+// readRouteSum summarizes each line of the inputs named in opts, or of in if none are. Lines that aren't understood
+// are handled as opts.onError says, being reported to errOut along with where they came from.
+func readRouteSum(in io.Reader, errOut io.Writer, opts options) (*routesum.RouteSum, error) {
+	rs := routesum.NewRouteSum(routeSumOptions(errOut, opts)...)
+	if len(opts.inputs) == 0 {
+		return rs, readInput(rs, in, stdinName, errOut, opts)
+	}
+
+	paths, err := inputPaths(opts.inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		if path == "-" {
+			if err := readInput(rs, in, stdinName, errOut, opts); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := readFile(rs, path, errOut, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+func readFile(rs *routesum.RouteSum, path string, errOut io.Writer, opts options) error {
+	f, err := os.Open(path) //nolint: gosec
+	if err != nil {
+		return fmt.Errorf("open input: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return readInput(rs, f, path, errOut, opts)
+}
+
+// readInput inserts each line of in into rs, naming in as name in errors and explanations.
+func readInput(rs *routesum.RouteSum, in io.Reader, name string, errOut io.Writer, opts options) error {
 	scanner := bufio.NewScanner(in)
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := bytes.TrimSpace(scanner.Bytes())
 		if len(line) == 0 {
 			continue
 		}
 
-		if err := rs.InsertFromString(string(line)); err != nil {
+		source := routesum.Source{File: name, Line: lineNum}
+		err := rs.InsertFromStringWithSource(string(line), source)
+		if err == nil {
+			continue
+		}
+
+		var iIErr *routesum.InvalidInputErr
+		if opts.onError == onErrorAbort || !errors.As(err, &iIErr) {
 			return fmt.Errorf("add string: %w", err)
 		}
+		if opts.onError == onErrorReport {
+			fmt.Fprintf(errOut, "%s\n", err.Error())
+		}
 	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// inputPaths expands input arguments into the files to read, in order. Arguments that aren't existing paths but
+// match a glob pattern are replaced by the files they match, directories by every file within them, recursively, in
+// lexical order, and - stands for standard input.
+func inputPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		if arg == "-" {
+			paths = append(paths, arg)
+			continue
+		}
+
+		matches, err := expandPattern(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			files, err := filesIn(match)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, files...)
+		}
+	}
+
+	return paths, nil
+}
+
+// expandPattern returns the paths matching a glob pattern. A path that exists is taken as itself, even if it looks
+// like a pattern, such as a file named [1].txt. So is a pattern matching nothing, for opening it to report on.
+func expandPattern(arg string) ([]string, error) {
+	if _, err := os.Stat(arg); err == nil {
+		return []string{arg}, nil
+	}
+
+	matches, err := filepath.Glob(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad pattern %q: %w", errInvalidArgs, arg, err)
+	}
+	if len(matches) == 0 {
+		return []string{arg}, nil
+	}
+
+	return matches, nil
+}
+
+// filesIn returns every file within a directory, recursively, in lexical order, or the path itself if it's not a
+// directory.
+func filesIn(path string) ([]string, error) {
+	// A path that can't be read is left for opening it to report on.
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// outputFormats returns the name of every output format.
+func outputFormats() []string {
+	return []string{
+		outputFormatCIDR,
+		outputFormatRange,
+		outputFormatNFT,
+		outputFormatIPSet,
+		outputFormatCisco,
+		outputFormatJunos,
+		outputFormatBIRD,
+		outputFormatFRR,
+		outputFormatAWSPrefixList,
+		outputFormatAWSSecurityGroup,
+		outputFormatGCPFirewall,
+		outputFormatAzureNSG,
+		outputFormatK8sNetworkPolicy,
+		outputFormatCalicoNetworkSet,
+	}
+}
 
-	for s := range rs.Each() {
+// newFormat returns the Format for opts.outputFormat, or nil if the output is a plain list. exclude, if not nil, is
+// left out by the formats that support it.
+func newFormat(opts options, exclude *routesum.RouteSum) format.Format {
+	switch opts.outputFormat {
+	case outputFormatNFT:
+		f := format.NewNFT(opts.name)
+		table := strings.Fields(opts.nftTable)
+		f.Family, f.Table = table[0], table[1]
+		return f
+	case outputFormatIPSet:
+		f := format.NewIPSet(opts.name)
+		f.MaxElem = opts.ipsetMaxElem
+		return f
+	case outputFormatCisco:
+		f := format.NewCisco(opts.name)
+		f.IPv4Bounds, f.IPv6Bounds = opts.ipv4Bounds, opts.ipv6Bounds
+		return f
+	case outputFormatJunos:
+		f := format.NewJunos(opts.name)
+		f.IPv4Bounds, f.IPv6Bounds = opts.ipv4Bounds, opts.ipv6Bounds
+		return f
+	case outputFormatBIRD:
+		f := format.NewBIRD(opts.name)
+		f.IPv4Bounds, f.IPv6Bounds = opts.ipv4Bounds, opts.ipv6Bounds
+		return f
+	case outputFormatFRR:
+		f := format.NewFRR(opts.name)
+		f.IPv4Bounds, f.IPv6Bounds = opts.ipv4Bounds, opts.ipv6Bounds
+		return f
+	}
+
+	if f := newCloudFormat(opts); f != nil {
+		return f
+	}
+
+	return newKubernetesFormat(opts, exclude)
+}
+
+// newCloudFormat returns the Format for opts.outputFormat if it's a cloud provider's, or nil otherwise.
+func newCloudFormat(opts options) format.Format {
+	switch opts.outputFormat {
+	case outputFormatAWSPrefixList:
+		f := format.NewAWSPrefixList(opts.name)
+		f.MaxEntries = cmp.Or(opts.chunkSize, f.MaxEntries)
+		return f
+	case outputFormatAWSSecurityGroup:
+		f := format.NewAWSSecurityGroup(opts.name)
+		f.MaxEntries = cmp.Or(opts.chunkSize, f.MaxEntries)
+		return f
+	case outputFormatGCPFirewall:
+		f := format.NewGCPFirewall(opts.name)
+		f.MaxEntries = cmp.Or(opts.chunkSize, f.MaxEntries)
+		return f
+	case outputFormatAzureNSG:
+		f := format.NewAzureNSG(opts.name)
+		f.MaxEntries = cmp.Or(opts.chunkSize, f.MaxEntries)
+		return f
+	}
+
+	return nil
+}
+
+// newKubernetesFormat returns the Format for opts.outputFormat if it's for Kubernetes, or nil otherwise.
+func newKubernetesFormat(opts options, exclude *routesum.RouteSum) format.Format {
+	switch opts.outputFormat {
+	case outputFormatK8sNetworkPolicy:
+		f := format.NewK8sNetworkPolicy(opts.name)
+		f.Namespace, f.Exclude = opts.namespace, exclude
+		return f
+	case outputFormatCalicoNetworkSet:
+		f := format.NewCalicoNetworkSet(opts.name)
+		f.Labels, f.Exclude = opts.labels, exclude
+		return f
+	}
+
+	return nil
+}
+
+// readExclusions returns a summary of the file named by --exclude, or nil if there isn't one.
+func readExclusions(errOut io.Writer, opts options) (*routesum.RouteSum, error) {
+	if opts.exclude == "" {
+		return nil, nil
+	}
+
+	rs := routesum.NewRouteSum(routeSumOptions(errOut, opts)...)
+	if err := readFile(rs, opts.exclude, errOut, opts); err != nil {
+		return nil, fmt.Errorf("read exclusions: %w", err)
+	}
+
+	return rs, nil
+}
+
+func writeRouteSum(out, errOut io.Writer, rs *routesum.RouteSum, opts options) error {
+	exclude, err := readExclusions(errOut, opts)
+	if err != nil {
+		return err
+	}
+
+	if f := newFormat(opts, exclude); f != nil {
+		if err := f.Write(out, rs); err != nil {
+			return fmt.Errorf("format output: %w", err)
+		}
+
+		return nil
+	}
+
+	var routes iter.Seq[string]
+	switch {
+	case opts.outputFormat == outputFormatRange && opts.descending:
+		routes = rangeStrings(rs.EachRangeDescending())
+	case opts.outputFormat == outputFormatRange:
+		routes = rangeStrings(rs.EachRange())
+	case opts.descending:
+		routes = rs.EachDescending()
+	default:
+		routes = rs.Each()
+	}
+
+	for s := range routes {
 		if _, err := out.Write([]byte(s + "\n")); err != nil {
 			return fmt.Errorf("write output: %w", err)
 		}
@@ -40,3 +792,73 @@ func summarize(in io.Reader, out io.Writer) error {
 
 	return nil
 }
+
+// rangeStrings formats each range of IPs.
+func rangeStrings(ranges iter.Seq[routesum.IPRange]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for r := range ranges {
+			if !yield(r.String()) {
+				return
+			}
+		}
+	}
+}
+
+// writeExplanation writes each summarized network followed by the input lines that contributed to it, and then the
+// input lines that were already covered.
+func writeExplanation(out io.Writer, rs *routesum.RouteSum, opts options) error {
+	explanation, err := rs.Explain()
+	if err != nil {
+		return fmt.Errorf("explain summary: %w", err)
+	}
+
+	if opts.descending {
+		slices.Reverse(explanation.Prefixes)
+	}
+
+	var b strings.Builder
+	for _, pe := range explanation.Prefixes {
+		fmt.Fprintf(&b, "%s\n", routesum.PrefixString(pe.Prefix))
+		for _, input := range pe.Contributors {
+			fmt.Fprintf(&b, "  %s %s\n", input.Source.String(), routesum.PrefixString(input.Prefix))
+		}
+	}
+	if len(explanation.Covered) > 0 {
+		b.WriteString("already covered:\n")
+		for _, input := range explanation.Covered {
+			fmt.Fprintf(&b, "  %s %s\n", input.Source.String(), routesum.PrefixString(input.Prefix))
+		}
+	}
+
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
+
+// writeStatsText writes stats as lines of text, listing prefix lengths in ascending order.
+func writeStatsText(out io.Writer, s routesum.Stats) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "inputs: %d\n", s.Inputs)
+	fmt.Fprintf(&b, "entries: %d\n", s.Entries)
+	for _, family := range []struct {
+		name  string
+		stats routesum.FamilyStats
+	}{
+		{name: "IPv4", stats: s.IPv4},
+		{name: "IPv6", stats: s.IPv6},
+	} {
+		fmt.Fprintf(&b, "%s entries: %d\n", family.name, family.stats.Entries)
+		fmt.Fprintf(&b, "%s addresses: %s\n", family.name, family.stats.Addresses.String())
+		for _, prefixLen := range slices.Sorted(maps.Keys(family.stats.PrefixLengths)) {
+			fmt.Fprintf(&b, "%s /%d entries: %d\n", family.name, prefixLen, family.stats.PrefixLengths[prefixLen])
+		}
+	}
+
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+	"github.com/PatrickCronin/routesum/pkg/routesum/format"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -13,8 +19,429 @@ func TestSummarize(t *testing.T) {
 	in := strings.NewReader(inStr)
 	var out strings.Builder
 
-	err := summarize(in, &out)
+	opts := defaultOptions()
+	err := summarize(in, &out, io.Discard, opts)
 	require.NoError(t, err, "summarize does not throw an error")
 
 	assert.Equal(t, "192.0.2.0/31\n", out.String(), "read expected output")
 }
+
+func TestRun(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name           string
+		args           []string
+		in             string
+		expected       string
+		expectedErrOut string
+	}{
+		{
+			name:     "no arguments summarizes",
+			args:     []string{},
+			in:       "192.0.2.0\n192.0.2.1\n",
+			expected: "192.0.2.0/31\n",
+		},
+		{
+			name:     "ranges",
+			args:     []string{},
+			in:       "192.0.2.10-192.0.2.13\n192.0.2.14 - 192.0.2.15\n",
+			expected: "192.0.2.10/31\n192.0.2.12/30\n",
+		},
+		{
+			name:     "netmasks and wildcard masks",
+			args:     []string{},
+			in:       "192.0.2.0 255.255.255.128\n192.0.2.128 0.0.0.127\n",
+			expected: "192.0.2.0/24\n",
+		},
+		{
+			name:           "host bits warning",
+			args:           []string{"--warn"},
+			in:             "192.0.2.0/25\n192.0.2.130/25\n",
+			expected:       "192.0.2.0/24\n",
+			expectedErrOut: "stdin:2: 192.0.2.130/25 has host bits set; using 192.0.2.128/25\n",
+		},
+		{
+			name:     "unmapping",
+			args:     []string{"--unmap", "mapped,nat64"},
+			in:       "192.0.2.0/25\n::ffff:192.0.2.128/121\n64:ff9b::198.51.100.1\n::203.0.113.1\n",
+			expected: "192.0.2.0/24\n198.51.100.1\n::cb00:7101\n",
+		},
+		{
+			name:     "embedded output",
+			args:     []string{"--unmap=mapped", "--embed-ipv4=mapped"},
+			in:       "192.0.2.0/25\n::ffff:192.0.2.128/121\n2001:db8::/32\n",
+			expected: "::ffff:192.0.2.0/120\n2001:db8::/32\n",
+		},
+		{
+			name:     "skipping errors",
+			args:     []string{"--on-error=skip"},
+			in:       "192.0.2.0\ngarbage\n192.0.2.1\n",
+			expected: "192.0.2.0/31\n",
+		},
+		{
+			name:     "reporting errors",
+			args:     []string{"--on-error", "report", "--strict"},
+			in:       "192.0.2.0\n192.0.2.5/24\n192.0.2.1\n",
+			expected: "192.0.2.0/31\n",
+			expectedErrOut: "stdin:2: '192.0.2.5/24' was not understood: host bits are set; the network is " +
+				"192.0.2.0/24.\n",
+		},
+		{
+			name:     "complement",
+			args:     []string{"complement"},
+			in:       "0.0.0.0/1\n128.0.0.0/2\n::/1\n",
+			expected: "192.0.0.0/2\n8000::/1\n",
+		},
+		{
+			name:     "complement of nothing",
+			args:     []string{"complement"},
+			in:       "",
+			expected: "0.0.0.0/0\n::/0\n",
+		},
+		{
+			name:     "descending",
+			args:     []string{"--descending"},
+			in:       "2001:db8::/32\n192.0.2.0/24\n198.51.100.0/24\n",
+			expected: "2001:db8::/32\n198.51.100.0/24\n192.0.2.0/24\n",
+		},
+		{
+			name:     "flags follow the mode",
+			args:     []string{"complement", "--descending"},
+			in:       "0.0.0.0/1\n::/1\n",
+			expected: "8000::/1\n128.0.0.0/1\n",
+		},
+		{
+			name:           "max entries",
+			args:           []string{"--max-entries", "2"},
+			in:             "192.0.2.0\n192.0.2.3\n198.51.100.0/24\n",
+			expected:       "192.0.2.0/30\n198.51.100.0/24\n",
+			expectedErrOut: "over-covered 2 addresses to fit 2 entries\n",
+		},
+		{
+			name:     "max entries already met",
+			args:     []string{"--max-entries=3"},
+			in:       "192.0.2.0\n192.0.2.3\n198.51.100.0/24\n",
+			expected: "192.0.2.0\n192.0.2.3\n198.51.100.0/24\n",
+		},
+		{
+			name:           "max over-cover ratio",
+			args:           []string{"--max-overcover-ratio", "0.25"},
+			in:             "192.0.2.0/24\n192.0.3.0/25\n198.51.100.1\n",
+			expected:       "192.0.2.0/23\n198.51.100.1\n",
+			expectedErrOut: "192.0.2.0/23 over-covers 128 addresses\n",
+		},
+		{
+			name: "explain",
+			args: []string{"--explain"},
+			in:   "192.0.2.0\n\n192.0.2.1\n192.0.2.0/31\n2001:db8::/32\n",
+			expected: "192.0.2.0/31\n  stdin:1 192.0.2.0\n  stdin:3 192.0.2.1\n" +
+				"2001:db8::/32\n  stdin:5 2001:db8::/32\n" +
+				"already covered:\n  stdin:4 192.0.2.0/31\n",
+		},
+		{
+			name:     "explain descending",
+			args:     []string{"--explain", "--descending"},
+			in:       "192.0.2.0/24\n2001:db8::/32\n",
+			expected: "2001:db8::/32\n  stdin:2 2001:db8::/32\n192.0.2.0/24\n  stdin:1 192.0.2.0/24\n",
+		},
+		{
+			name:     "range output",
+			args:     []string{"--output-format", "range"},
+			in:       "192.0.2.10/31\n192.0.2.12/30\n192.0.2.17\n2001:db8::/127\n2001:db8::2\n",
+			expected: "192.0.2.10-192.0.2.15\n192.0.2.17-192.0.2.17\n2001:db8::-2001:db8::2\n",
+		},
+		{
+			name:     "range output descending",
+			args:     []string{"--output-format=range", "--descending"},
+			in:       "192.0.2.10/31\n192.0.2.12/30\n192.0.2.17\n2001:db8::/127\n2001:db8::2\n",
+			expected: "2001:db8::-2001:db8::2\n192.0.2.17-192.0.2.17\n192.0.2.10-192.0.2.15\n",
+		},
+		{
+			name: "nft output",
+			args: []string{"--output-format=nft", "--name=blocklist", "--nft-table", "ip fw"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n2001:db8::1\n",
+			expected: "#!/usr/sbin/nft -f\n\n" +
+				"table ip fw {\n" +
+				"\tset blocklist_v4 {\n\t\ttype ipv4_addr\n\t\tflags interval\n\t}\n" +
+				"\tset blocklist_v6 {\n\t\ttype ipv6_addr\n\t\tflags interval\n\t}\n" +
+				"}\n\n" +
+				"flush set ip fw blocklist_v4\nflush set ip fw blocklist_v6\n" +
+				"add element ip fw blocklist_v4 {\n\t192.0.2.0/24\n}\n" +
+				"add element ip fw blocklist_v6 {\n\t2001:db8::1\n}\n",
+		},
+		{
+			name: "ipset output",
+			args: []string{"--output-format=ipset", "--name=blocklist", "--ipset-maxelem=1024"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n",
+			expected: "create blocklist_v4 hash:net family inet maxelem 1024 -exist\n" +
+				"create blocklist_v4_tmp hash:net family inet maxelem 1024 -exist\n" +
+				"flush blocklist_v4_tmp\nadd blocklist_v4_tmp 192.0.2.0/24\n" +
+				"swap blocklist_v4_tmp blocklist_v4\ndestroy blocklist_v4_tmp\n" +
+				"create blocklist_v6 hash:net family inet6 maxelem 1024 -exist\n" +
+				"create blocklist_v6_tmp hash:net family inet6 maxelem 1024 -exist\n" +
+				"flush blocklist_v6_tmp\nswap blocklist_v6_tmp blocklist_v6\ndestroy blocklist_v6_tmp\n",
+		},
+		{
+			name: "cisco output",
+			args: []string{"--output-format=cisco", "--name=bogons", "--ipv4-le=28"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n2001:db8::/32\n",
+			expected: "no ip prefix-list bogons_v4\nip prefix-list bogons_v4 seq 5 permit 192.0.2.0/24 le 28\n" +
+				"ip prefix-list bogons_v4 seq 10 deny 0.0.0.0/0 le 32\n" +
+				"no ipv6 prefix-list bogons_v6\nipv6 prefix-list bogons_v6 seq 5 permit 2001:db8::/32\n" +
+				"ipv6 prefix-list bogons_v6 seq 10 deny ::/0 le 128\n",
+		},
+		{
+			name: "junos output",
+			args: []string{"--output-format=junos", "--name=bogons"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n",
+			expected: "delete policy-options prefix-list bogons\n" +
+				"set policy-options prefix-list bogons 192.0.2.0/24\n",
+		},
+		{
+			name:     "bird output",
+			args:     []string{"--output-format=bird", "--name=bogons", "--ipv6-ge=48", "--ipv6-le=64"},
+			in:       "2001:db8::/32\n",
+			expected: "define bogons_v6 = [\n\t2001:db8::/32{48,64}\n];\n",
+		},
+		{
+			name: "frr output",
+			args: []string{"--output-format=frr", "--name=bogons"},
+			in:   "192.0.2.0/24\n",
+			expected: "no ip prefix-list bogons_v4\nip prefix-list bogons_v4 seq 5 permit 192.0.2.0/24\n" +
+				"ip prefix-list bogons_v4 seq 10 deny 0.0.0.0/0 le 32\n" +
+				"no ipv6 prefix-list bogons_v6\nipv6 prefix-list bogons_v6 seq 5 deny ::/0 le 128\n",
+		},
+		{
+			name: "gcp-firewall output",
+			args: []string{"--output-format=gcp-firewall", "--name=blocklist", "--chunk-size=1"},
+			in:   "192.0.2.0/24\n198.51.100.0/24\n",
+			expected: "[\n" +
+				"  {\n    \"name\": \"blocklist-v4-1\",\n    \"direction\": \"INGRESS\",\n" +
+				"    \"sourceRanges\": [\n      \"192.0.2.0/24\"\n    ]\n  },\n" +
+				"  {\n    \"name\": \"blocklist-v4-2\",\n    \"direction\": \"INGRESS\",\n" +
+				"    \"sourceRanges\": [\n      \"198.51.100.0/24\"\n    ]\n  }\n" +
+				"]\n",
+		},
+		{
+			name: "calico-networkset output",
+			args: []string{"--output-format=calico-networkset", "--name=blocklist", "--label=role=blocklist"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n",
+			expected: "apiVersion: projectcalico.org/v3\nkind: GlobalNetworkSet\nmetadata:\n  name: blocklist\n" +
+				"  labels:\n    \"role\": \"blocklist\"\n" +
+				"spec:\n  nets:\n    - \"192.0.2.0/24\"\n",
+		},
+		{
+			name: "stats",
+			args: []string{"stats"},
+			in:   "192.0.2.0/25\n192.0.2.128/25\n192.0.2.7\n2001:db8::/32\n",
+			expected: "inputs: 4\nentries: 2\n" +
+				"IPv4 entries: 1\nIPv4 addresses: 256\nIPv4 /24 entries: 1\n" +
+				"IPv6 entries: 1\nIPv6 addresses: 79228162514264337593543950336\nIPv6 /32 entries: 1\n",
+		},
+		{
+			name: "stats as JSON",
+			args: []string{"stats", "--json"},
+			in:   "192.0.2.0\n192.0.2.1\n",
+			expected: `{
+  "inputs": 2,
+  "entries": 1,
+  "ipv4": {
+    "entries": 1,
+    "addresses": 2,
+    "prefixLengths": {
+      "31": 1
+    }
+  },
+  "ipv6": {
+    "entries": 0,
+    "addresses": 0,
+    "prefixLengths": {}
+  }
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out, errOut strings.Builder
+			err := run(test.args, strings.NewReader(test.in), &out, &errOut)
+			require.NoError(t, err, "run does not throw an error")
+			assert.Equal(t, test.expected, out.String(), "read expected output")
+			assert.Equal(t, test.expectedErrOut, errOut.String(), "read expected error output")
+		})
+	}
+
+	err := run([]string{"bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "unrecognized modes are read as input files")
+
+	err = run([]string{"complement", "extra"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "arguments after the mode are read as input files")
+
+	err = run([]string{"[bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	if assert.ErrorIs(t, err, errInvalidArgs, "malformed glob patterns are rejected") {
+		assert.ErrorIs(t, err, filepath.ErrBadPattern)
+		assert.Contains(t, err.Error(), `"[bogus"`, "the pattern is named")
+	}
+
+	err = run([]string{"--bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.Error(t, err, "unrecognized flags are rejected")
+
+	err = run([]string{"--max-entries=-1"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "negative maximum entries are rejected")
+
+	err = run([]string{"--max-overcover-ratio=2"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, routesum.ErrInvalidRatio, "ratios above 1 are rejected")
+
+	err = run([]string{"complement", "--explain"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "complement can't be explained")
+
+	err = run([]string{"--json"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "only stats can be output as JSON")
+
+	err = run([]string{"--output-format=bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "unknown output formats are rejected")
+
+	err = run([]string{"stats", "--output-format=range"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "stats can't be output as ranges")
+
+	err = run([]string{"--strict"}, strings.NewReader("192.0.2.0/24\n192.0.2.5/24\n"), io.Discard, io.Discard)
+	var iIErr *routesum.InvalidInputErr
+	if assert.ErrorAs(t, err, &iIErr, "strict mode rejects host bits") {
+		assert.Contains(t, err.Error(), "stdin:2", "the line is reported")
+		assert.Equal(t, "192.0.2.5/24", iIErr.InvalidValue, "the value is reported")
+	}
+
+	err = run([]string{"--unmap=mapped,bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "unknown embeddings are rejected")
+
+	err = run([]string{"--on-error=bogus"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "unknown error actions are rejected")
+
+	err = run([]string{}, strings.NewReader("192.0.2.0\ngarbage\n"), io.Discard, io.Discard)
+	if assert.ErrorAs(t, err, &iIErr, "errors abort by default") {
+		assert.Equal(t, routesum.ReasonInvalidIP, iIErr.Code, "the reason is reported")
+		assert.Equal(t, routesum.Source{File: stdinName, Line: 2}, iIErr.Source, "the line is reported")
+	}
+
+	err = run([]string{"--output-format=nft", "--descending"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "nft output has no order to reverse")
+
+	err = run([]string{"--nft-table=filter"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "nft tables need a family")
+
+	err = run([]string{"--output-format=nft", "--name=a;b"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, format.ErrInvalidName, "names nft can't use are rejected")
+
+	err = run([]string{"--output-format=cisco", "--ipv4-le=33"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, format.ErrInvalidBounds, "bounds must fit the address family")
+
+	err = run([]string{"--chunk-size=-1"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "negative chunk sizes are rejected")
+
+	err = run([]string{"--label=role"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errInvalidFlagValue, "labels need a value")
+
+	err = run([]string{"--exclude=a.txt"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "only Kubernetes formats exclude")
+
+	err = run([]string{"--output-format=k8s-networkpolicy"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, format.ErrEmptySummary, "a NetworkPolicy must block something")
+
+	err = run([]string{"--strict", "--warn"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, errIncompatibleArgs, "strict mode can't warn")
+
+	err = run([]string{"--max-entries=1"}, strings.NewReader("192.0.2.0\n2001:db8::\n"), io.Discard, io.Discard)
+	assert.Error(t, err, "too few maximum entries are rejected")
+}
+
+func TestInputFiles(t *testing.T) { //nolint: funlen
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	a := writeFile("a.txt", "192.0.2.0\n")
+	b := writeFile("b.txt", "192.0.2.1\n")
+	writeFile("feeds/c.txt", "198.51.100.0/25\n")
+	writeFile("feeds/nested/d.txt", "198.51.100.128/25\n")
+	bad := writeFile("bad.txt", "192.0.2.2\ngarbage\n")
+	bracketed := writeFile("[feed].txt", "203.0.113.0\n")
+	writeFile("e.txt", "203.0.113.1\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		in       string
+		expected string
+	}{
+		{
+			name:     "files",
+			args:     []string{a, b},
+			expected: "192.0.2.0/31\n",
+		},
+		{
+			name:     "standard input among files",
+			args:     []string{a, "-"},
+			in:       "192.0.2.1\n",
+			expected: "192.0.2.0/31\n",
+		},
+		{
+			name:     "glob",
+			args:     []string{filepath.Join(dir, "[ab].txt")},
+			expected: "192.0.2.0/31\n",
+		},
+		{
+			name:     "existing path that looks like a glob",
+			args:     []string{bracketed},
+			expected: "203.0.113.0\n",
+		},
+		{
+			name:     "directory",
+			args:     []string{filepath.Join(dir, "feeds")},
+			expected: "198.51.100.0/24\n",
+		},
+		{
+			name: "exclusions",
+			args: []string{"--output-format=k8s-networkpolicy", "--name=egress", "--exclude", b, a, b},
+			expected: "apiVersion: networking.k8s.io/v1\nkind: NetworkPolicy\nmetadata:\n  name: egress\n" +
+				"spec:\n  podSelector: {}\n  policyTypes:\n    - Egress\n" +
+				"  egress:\n    - to:\n        - ipBlock:\n            cidr: \"0.0.0.0/0\"\n" +
+				"            except:\n              - \"192.0.2.0/32\"\n" +
+				"        - ipBlock:\n            cidr: \"::/0\"\n",
+		},
+		{
+			name:     "mode and flags before files",
+			args:     []string{"complement", "--descending", "--max-entries=2", a, b},
+			expected: "::/0\n0.0.0.0/0\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			err := run(test.args, strings.NewReader(test.in), &out, io.Discard)
+			require.NoError(t, err, "run does not throw an error")
+			assert.Equal(t, test.expected, out.String(), "read expected output")
+		})
+	}
+
+	err := run([]string{a, bad}, strings.NewReader(""), io.Discard, io.Discard)
+	var iIErr *routesum.InvalidInputErr
+	if assert.ErrorAs(t, err, &iIErr, "bad input is rejected") {
+		assert.Equal(t, routesum.Source{File: bad, Line: 2}, iIErr.Source, "the file and line are reported")
+	}
+
+	var errOut strings.Builder
+	err = run([]string{"--on-error=report", bad}, strings.NewReader(""), io.Discard, &errOut)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		bad+`:2: 'garbage' was not understood: ParseAddr("garbage"): unable to parse IP.`+"\n",
+		errOut.String(),
+		"reported errors name the file",
+	)
+
+	err = run([]string{filepath.Join(dir, "missing.txt")}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "missing files are rejected")
+}
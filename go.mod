@@ -2,10 +2,7 @@ module github.com/PatrickCronin/routesum
 
 go 1.24
 
-require (
-	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.11.1
-)
+require github.com/stretchr/testify v1.11.1
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
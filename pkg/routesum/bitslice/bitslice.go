@@ -1,51 +1,176 @@
 // Package bitslice provides a slice of bits
 package bitslice
 
-// BitSlice is a slice of zeros and ones
-type BitSlice []byte
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// MaxLen is the most bits a BitSlice can hold: enough for an IPv6 address.
+const MaxLen = 128
+
+// ErrTooLong is returned when more than MaxLen bits would be needed.
+var ErrTooLong = errors.New("too many bits")
+
+// BitSlice is a slice of zeros and ones, of at most MaxLen bits. The bits are packed into two words, the first bit
+// being the most significant bit of hi, and bits past the end are always zero, so BitSlices can be compared with ==.
+// BitSlice is a value type: its methods return new BitSlices rather than modifying it. The zero value is empty.
+type BitSlice struct {
+	hi, lo uint64
+	len    uint8
+}
+
+// New creates a BitSlice from zeros and ones. Anything other than a zero is taken to be a one. New panics if given
+// more than MaxLen bits.
+func New(bitValues ...byte) BitSlice {
+	if len(bitValues) > MaxLen {
+		panic(fmt.Sprintf("bitslice: %d bits is more than %d", len(bitValues), MaxLen))
+	}
+
+	var b BitSlice
+	for i, bit := range bitValues {
+		if bit == 0 {
+			continue
+		}
+
+		if i < 64 {
+			b.hi |= 1 << (63 - i)
+		} else {
+			b.lo |= 1 << (127 - i)
+		}
+	}
+	b.len = uint8(len(bitValues)) //nolint: gosec
+
+	return b
+}
 
 // NewFromBytes creates a BitSlice from a byte slice
 func NewFromBytes(bytes []byte) (BitSlice, error) {
-	bits := make(BitSlice, 0, len(bytes)*8)
-
-	// Convert each byte to its binary representation as bits
-	for _, b := range bytes {
-		bits = append(
-			bits,
-			b&0b10000000>>7,
-			b&0b01000000>>6,
-			b&0b00100000>>5,
-			b&0b00010000>>4,
-			b&0b00001000>>3,
-			b&0b00000100>>2,
-			b&0b00000010>>1,
-			b&0b00000001,
-		)
+	if len(bytes)*8 > MaxLen {
+		return BitSlice{}, fmt.Errorf("%w: %d bytes", ErrTooLong, len(bytes))
 	}
 
-	return bits, nil
+	var padded [MaxLen / 8]byte
+	copy(padded[:], bytes)
+
+	return BitSlice{
+		hi:  binary.BigEndian.Uint64(padded[:8]),
+		lo:  binary.BigEndian.Uint64(padded[8:]),
+		len: uint8(len(bytes) * 8), //nolint: gosec
+	}, nil
 }
 
 // ToBytes packages a BitSlice as a byte slice of length numBytes
 func (b BitSlice) ToBytes(numBytes int) []byte {
-	// Ensure we're working with enough bits for the requested bytes
-	completeBits := make([]byte, numBytes*8)
-	copy(completeBits, b)
-
-	bytes := make([]byte, 0, numBytes)
-	for i := 0; i < numBytes; i++ {
-		bytes = append(
-			bytes,
-			completeBits[i*8]<<7+
-				completeBits[i*8+1]<<6+
-				completeBits[i*8+2]<<5+
-				completeBits[i*8+3]<<4+
-				completeBits[i*8+4]<<3+
-				completeBits[i*8+5]<<2+
-				completeBits[i*8+6]<<1+
-				completeBits[i*8+7],
-		)
-	}
+	var packed [MaxLen / 8]byte
+	binary.BigEndian.PutUint64(packed[:8], b.hi)
+	binary.BigEndian.PutUint64(packed[8:], b.lo)
+
+	bytes := make([]byte, numBytes)
+	copy(bytes, packed[:])
 
 	return bytes
 }
+
+// Len returns the number of bits in the BitSlice.
+func (b BitSlice) Len() int {
+	return int(b.len)
+}
+
+// Bit returns the bit at index i, as a 0 or 1. It panics if i is out of range.
+func (b BitSlice) Bit(i int) byte {
+	if i < 0 || i >= int(b.len) {
+		panic(fmt.Sprintf("bitslice: index %d out of range with length %d", i, b.len))
+	}
+
+	if i < 64 {
+		return byte(b.hi >> (63 - i) & 1)
+	}
+
+	return byte(b.lo >> (127 - i) & 1)
+}
+
+// Prefix returns the first n bits of the BitSlice, like b[:n] would for a slice. It panics if n is out of range.
+func (b BitSlice) Prefix(n int) BitSlice {
+	if n < 0 || n > int(b.len) {
+		panic(fmt.Sprintf("bitslice: prefix length %d out of range with length %d", n, b.len))
+	}
+
+	// Shifts of a whole word or more leave nothing, which masks off everything as needed.
+	if n <= 64 {
+		return BitSlice{hi: b.hi &^ (^uint64(0) >> n), lo: 0, len: uint8(n)} //nolint: gosec
+	}
+
+	return BitSlice{hi: b.hi, lo: b.lo &^ (^uint64(0) >> (n - 64)), len: uint8(n)} //nolint: gosec
+}
+
+// Suffix returns the BitSlice without its first n bits, like b[n:] would for a slice. It panics if n is out of range.
+func (b BitSlice) Suffix(n int) BitSlice {
+	if n < 0 || n > int(b.len) {
+		panic(fmt.Sprintf("bitslice: suffix start %d out of range with length %d", n, b.len))
+	}
+
+	hi, lo := shiftLeft(b.hi, b.lo, n)
+	return BitSlice{hi: hi, lo: lo, len: b.len - uint8(n)} //nolint: gosec
+}
+
+// Append returns b followed by other. It panics if the result would be longer than MaxLen.
+func (b BitSlice) Append(other BitSlice) BitSlice {
+	if int(b.len)+int(other.len) > MaxLen {
+		panic(fmt.Sprintf("bitslice: appending %d bits to %d is more than %d", other.len, b.len, MaxLen))
+	}
+
+	hi, lo := shiftRight(other.hi, other.lo, int(b.len))
+	return BitSlice{hi: b.hi | hi, lo: b.lo | lo, len: b.len + other.len}
+}
+
+// AppendBit returns b followed by a single bit, which is taken to be a one if it isn't a zero.
+func (b BitSlice) AppendBit(bit byte) BitSlice {
+	return b.Append(New(bit))
+}
+
+// HasPrefix reports whether b begins with prefix.
+func (b BitSlice) HasPrefix(prefix BitSlice) bool {
+	return prefix.len <= b.len && b.Prefix(int(prefix.len)) == prefix
+}
+
+// CommonPrefixLen returns the number of leading bits b and other have in common.
+func (b BitSlice) CommonPrefixLen(other BitSlice) int {
+	common := 64 + bits.LeadingZeros64(b.lo^other.lo)
+	if diff := b.hi ^ other.hi; diff != 0 {
+		common = bits.LeadingZeros64(diff)
+	}
+
+	return min(common, int(b.len), int(other.len))
+}
+
+// String returns the bits as a string of zeros and ones.
+func (b BitSlice) String() string {
+	var s strings.Builder
+	for i := range int(b.len) {
+		s.WriteByte('0' + b.Bit(i))
+	}
+
+	return s.String()
+}
+
+// shiftLeft shifts a 128-bit value, given as two words, n bits towards its most significant bit.
+func shiftLeft(hi, lo uint64, n int) (uint64, uint64) {
+	if n >= 64 {
+		return lo << (n - 64), 0
+	}
+
+	return hi<<n | lo>>(64-n), lo << n
+}
+
+// shiftRight shifts a 128-bit value, given as two words, n bits towards its least significant bit.
+func shiftRight(hi, lo uint64, n int) (uint64, uint64) {
+	if n >= 64 {
+		return 0, hi >> (n - 64)
+	}
+
+	return hi >> n, lo>>n | hi<<(64-n)
+}
@@ -1,7 +1,9 @@
 package bitslice
 
 import (
+	"math/rand/v2"
 	"net/netip"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,17 +19,17 @@ func TestNewBitSliceFromBytes(t *testing.T) { //nolint: funlen
 		{
 			name: "IPv4",
 			ip:   "192.0.2.37",
-			expected: BitSlice([]byte{
+			expected: New(
 				1, 1, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
 				0, 0, 1, 0, 0, 1, 0, 1,
-			}),
+			),
 		},
 		{
 			name: "IPv6-embedded IPv4",
 			ip:   "::ffff:192.0.2.0",
-			expected: BitSlice([]byte{
+			expected: New(
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
@@ -44,12 +46,12 @@ func TestNewBitSliceFromBytes(t *testing.T) { //nolint: funlen
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
-			}),
+			),
 		},
 		{
 			name: "IPv6",
 			ip:   "2001:db8::1",
-			expected: BitSlice([]byte{
+			expected: New(
 				0, 0, 1, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 1,
 				0, 0, 0, 0, 1, 1, 0, 1,
@@ -66,7 +68,7 @@ func TestNewBitSliceFromBytes(t *testing.T) { //nolint: funlen
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 1,
-			}),
+			),
 		},
 	}
 
@@ -93,28 +95,28 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 	}{
 		{
 			name: "IPv4 IP",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				1, 1, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
 				0, 0, 1, 0, 0, 1, 0, 1,
-			}),
+			),
 			numBytes: 4,
 			expected: []byte{192, 0, 2, 37},
 		},
 		{
 			name: "IPv4 network",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				1, 1, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
-			}),
+			),
 			numBytes: 4,
 			expected: []byte{192, 0, 2, 0},
 		},
 		{
 			name: "IPv4-embedded IPv6 IP",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
@@ -131,13 +133,13 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
 				0, 0, 1, 0, 0, 1, 0, 1,
-			}),
+			),
 			numBytes: 16,
 			expected: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 255, 192, 0, 2, 37},
 		},
 		{
 			name: "IPv4-embedded IPv6 network",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
@@ -153,13 +155,13 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 				1, 1, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 1, 0,
-			}),
+			),
 			numBytes: 16,
 			expected: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 255, 192, 0, 2, 0},
 		},
 		{
 			name: "IPv6 IP",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				0, 0, 1, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 1,
 				0, 0, 0, 0, 1, 1, 0, 1,
@@ -176,13 +178,13 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 1,
-			}),
+			),
 			numBytes: 16,
 			expected: []byte{0x20, 0x1, 0xd, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
 		},
 		{
 			name: "IPv6 network",
-			bitSlice: BitSlice([]byte{
+			bitSlice: New(
 				0, 0, 1, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 1,
 				0, 0, 0, 0, 1, 1, 0, 1,
@@ -191,7 +193,7 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
-			}),
+			),
 			numBytes: 16,
 			expected: []byte{0x20, 0x1, 0xd, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 		},
@@ -203,3 +205,96 @@ func TestToBytes(t *testing.T) { //nolint: funlen
 		})
 	}
 }
+
+func TestOperations(t *testing.T) { //nolint: funlen
+	b := New(1, 0, 1, 1)
+	assert.Equal(t, 4, b.Len(), "got expected length")
+	assert.Equal(t, byte(1), b.Bit(2), "got expected bit")
+	assert.Equal(t, New(1, 0), b.Prefix(2), "got expected prefix")
+	assert.Equal(t, New(1, 1), b.Suffix(2), "got expected suffix")
+	assert.Equal(t, New(1, 0, 1, 1, 0, 1), b.Append(New(0, 1)), "got expected concatenation")
+	assert.Equal(t, New(1, 0, 1, 1, 0), b.AppendBit(0), "got expected concatenation")
+	assert.True(t, b.HasPrefix(New(1, 0)), "has prefix")
+	assert.False(t, b.HasPrefix(New(1, 1)), "doesn't have differing prefix")
+	assert.False(t, New(1).HasPrefix(b), "doesn't have longer prefix")
+	assert.Equal(t, 2, b.CommonPrefixLen(New(1, 0, 0)), "got expected common prefix length")
+	assert.Equal(t, "1011", b.String(), "got expected string")
+	assert.Equal(t, BitSlice{}, New(), "zero value is empty")
+
+	assert.Panics(t, func() { b.Bit(4) }, "out of range bit panics")
+	assert.Panics(t, func() { b.Prefix(5) }, "out of range prefix panics")
+	assert.Panics(t, func() { b.Suffix(5) }, "out of range suffix panics")
+	assert.Panics(t, func() { New(make([]byte, MaxLen)...).AppendBit(0) }, "too long concatenation panics")
+
+	_, err := NewFromBytes(make([]byte, MaxLen/8+1))
+	assert.ErrorIs(t, err, ErrTooLong, "too many bytes are rejected")
+
+	// Compare against slices of bits, straddling the boundary between the words.
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint: gosec
+	randomBits := func() []byte {
+		bitValues := make([]byte, rng.IntN(MaxLen+1))
+		for i := range bitValues {
+			bitValues[i] = byte(rng.IntN(2))
+		}
+		return bitValues
+	}
+	for range 1000 {
+		aBits, bBits := randomBits(), randomBits()
+		a := New(aBits...)
+		n := rng.IntN(len(aBits) + 1)
+
+		for i := range aBits {
+			require.Equal(t, aBits[i], a.Bit(i), "bit %d of %v", i, aBits)
+		}
+		require.Equal(t, New(aBits[:n]...), a.Prefix(n), "%d-bit prefix of %v", n, aBits)
+		require.Equal(t, New(aBits[n:]...), a.Suffix(n), "%d-bit suffix of %v", n, aBits)
+
+		if len(aBits[:n])+len(bBits) <= MaxLen {
+			expected := New(slices.Concat(aBits[:n], bBits)...)
+			require.Equal(t, expected, a.Prefix(n).Append(New(bBits...)), "%v and %v", aBits[:n], bBits)
+		}
+
+		common := 0
+		for common < min(len(aBits), len(bBits)) && aBits[common] == bBits[common] {
+			common++
+		}
+		require.Equal(t, common, a.CommonPrefixLen(New(bBits...)), "%v and %v", aBits, bBits)
+		require.Equal(t, common == len(bBits), a.HasPrefix(New(bBits...)), "%v and %v", aBits, bBits)
+	}
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     BitSlice
+		expected int
+	}{
+		{
+			name:     "differing first bit",
+			a:        New(0),
+			b:        New(1),
+			expected: 0,
+		},
+		{
+			name:     "differing second bit",
+			a:        New(0, 0),
+			b:        New(0, 1),
+			expected: 1,
+		},
+		{
+			name:     "nothing different",
+			a:        New(0, 0, 0, 1),
+			b:        New(0, 0, 0, 1),
+			expected: 4,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(
+			t,
+			test.expected,
+			test.a.CommonPrefixLen(test.b),
+			test.name,
+		)
+	}
+}
@@ -0,0 +1,144 @@
+package routesum
+
+import (
+	"net/netip"
+	"slices"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
+)
+
+// Embedding is a way of embedding IPv4 addresses in the last 32 bits of IPv6 addresses.
+type Embedding int
+
+const (
+	// EmbeddingMapped is IPv4-mapped IPv6 addresses, in ::ffff:0:0/96, such as ::ffff:192.0.2.1.
+	EmbeddingMapped Embedding = iota + 1
+	// EmbeddingCompatible is the deprecated IPv4-compatible IPv6 addresses, in ::/96, such as ::192.0.2.1. Note that
+	// ::/96 also holds :: and ::1.
+	EmbeddingCompatible
+	// EmbeddingNAT64 is the NAT64 well-known prefix, 64:ff9b::/96, such as 64:ff9b::c000:201.
+	EmbeddingNAT64
+)
+
+// Embeddings returns every Embedding.
+func Embeddings() []Embedding {
+	return []Embedding{EmbeddingMapped, EmbeddingCompatible, EmbeddingNAT64}
+}
+
+// String returns the name of the embedding: mapped, compatible or nat64.
+func (e Embedding) String() string {
+	switch e {
+	case EmbeddingMapped:
+		return "mapped"
+	case EmbeddingCompatible:
+		return "compatible"
+	case EmbeddingNAT64:
+		return "nat64"
+	}
+
+	return "unknown"
+}
+
+// Prefix returns the IPv6 network IPv4 addresses are embedded in.
+func (e Embedding) Prefix() netip.Prefix {
+	switch e {
+	case EmbeddingMapped:
+		return netip.MustParsePrefix("::ffff:0:0/96")
+	case EmbeddingCompatible:
+		return netip.MustParsePrefix("::/96")
+	case EmbeddingNAT64:
+		return netip.MustParsePrefix("64:ff9b::/96")
+	}
+
+	return netip.Prefix{}
+}
+
+// WithUnmapping makes the RouteSum treat IPv6 networks and IPs embedding IPv4 ones in any of the given ways as the
+// IPv4 networks and IPs they embed, so that, for example, ::ffff:192.0.2.0/120 and 192.0.2.0/24 are the same network.
+// Unmapping applies to everything the RouteSum is given, including networks removed and looked up. Networks shorter
+// than the /96 an embedding uses, such as ::/64, aren't unmapped. RouteSums made from this one by set operations
+// unmap the same way.
+func WithUnmapping(embeddings ...Embedding) Option {
+	return func(rs *RouteSum) {
+		rs.unmapping = slices.Clone(embeddings)
+	}
+}
+
+// WithEmbeddedOutput makes the RouteSum output IPv4 networks and IPs as the IPv6 ones embedding them in the given way.
+// For example, with EmbeddingMapped, 192.0.2.0/24 is output as ::ffff:192.0.2.0/120. Output networks take their place
+// in address order among the IPv6 networks, merging with those next to them and left out where one covers them. The
+// RouteSum also unmaps the given embedding, so that networks given in it are stored with the IPv4 networks output in
+// it. RouteSums made from this one by set operations output the same way.
+func WithEmbeddedOutput(embedding Embedding) Option {
+	return func(rs *RouteSum) {
+		rs.embeddedOutput = embedding
+	}
+}
+
+// unmappedEmbedding returns the way an IP is embedding an IPv4 one, if the RouteSum unmaps it, and otherwise 0.
+func (rs *RouteSum) unmappedEmbedding(ip netip.Addr) Embedding {
+	if !ip.Is6() {
+		return 0
+	}
+
+	for _, e := range rs.unmapping {
+		if e.Prefix().Contains(ip) {
+			return e
+		}
+	}
+	if rs.embeddedOutput.Prefix().Contains(ip) {
+		return rs.embeddedOutput
+	}
+
+	return 0
+}
+
+// unmap returns the IPv4 network embedded in an IPv6 network, if the RouteSum unmaps the way it's embedded, and
+// otherwise the network itself.
+func (rs *RouteSum) unmap(ipPrefix netip.Prefix) netip.Prefix {
+	if ipPrefix.Bits() < 96 || rs.unmappedEmbedding(ipPrefix.Addr()) == 0 {
+		return ipPrefix
+	}
+
+	addrBytes := ipPrefix.Addr().As16()
+	return netip.PrefixFrom(netip.AddrFrom4([4]byte(addrBytes[12:])), ipPrefix.Bits()-96)
+}
+
+// render returns an IPv4 network as the IPv6 network embedding it, if the RouteSum outputs them that way, and
+// otherwise the network itself.
+func (rs *RouteSum) render(ipPrefix netip.Prefix) netip.Prefix {
+	return embed(ipPrefix, rs.embeddedOutput)
+}
+
+// embed returns an IPv4 network as the IPv6 network embedding it in the given way, or the network itself if it isn't
+// an IPv4 network or there's no such embedding.
+func embed(ipPrefix netip.Prefix, embedding Embedding) netip.Prefix {
+	embeddingPrefix := embedding.Prefix()
+	if !ipPrefix.Addr().Is4() || !embeddingPrefix.IsValid() {
+		return ipPrefix
+	}
+
+	addrBytes := embeddingPrefix.Addr().As16()
+	ipv4Bytes := ipPrefix.Addr().As4()
+	copy(addrBytes[12:], ipv4Bytes[:])
+
+	return netip.PrefixFrom(netip.AddrFrom16(addrBytes), ipPrefix.Bits()+96)
+}
+
+// outputTries returns the tries networks are output from. If the RouteSum outputs IPv4 networks embedded in IPv6,
+// they're moved into a copy of the IPv6 trie, which summarizes them along with the IPv6 networks, leaving the IPv4
+// trie empty. Otherwise, they're the tries the networks are stored in.
+func (rs *RouteSum) outputTries() (*rstrie.RSTrie, *rstrie.RSTrie) {
+	if !rs.embeddedOutput.Prefix().IsValid() {
+		return rs.ipv4, rs.ipv6
+	}
+
+	// An embedding's network is always valid, so it can always be expressed as bits.
+	embeddingBits, _ := ipBitsForIPPrefix(rs.embeddedOutput.Prefix())
+	embedded := rstrie.NewRSTrie()
+	for bits := range rs.ipv4.Each() {
+		embedded.InsertRoute(embeddingBits.Append(bits))
+	}
+
+	return rstrie.NewRSTrie(), rs.ipv6.Union(embedded)
+}
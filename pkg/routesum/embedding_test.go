@@ -0,0 +1,149 @@
+package routesum
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmapping(t *testing.T) {
+	inputs := []string{
+		"192.0.2.0/25",
+		"::ffff:192.0.2.128/121",
+		"::198.51.100.1",
+		"64:ff9b::203.0.113.0/120",
+		"::fffe:0:0/95",
+		"2001:db8::1",
+	}
+
+	rs := NewRouteSum()
+	for _, s := range inputs {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"192.0.2.0/25",
+			"::c633:6401",
+			"::fffe:0:0/95",
+			"64:ff9b::cb00:7100/120",
+			"2001:db8::1",
+		},
+		rs.SummaryStrings(),
+		"nothing is unmapped by default",
+	)
+
+	rs = NewRouteSum(WithUnmapping(EmbeddingMapped, EmbeddingCompatible, EmbeddingNAT64))
+	for _, s := range inputs {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"192.0.2.0/24",
+			"198.51.100.1",
+			"203.0.113.0/24",
+			"::fffe:0:0/95",
+			"2001:db8::1",
+		},
+		rs.SummaryStrings(),
+		"embedded networks are unmapped, but shorter networks aren't",
+	)
+	assert.True(t, rs.Contains(netip.MustParseAddr("::ffff:192.0.2.1")), "lookups are unmapped")
+	require.NoError(t, rs.RemoveFromString("64:ff9b::203.0.113.0/121"))
+	assert.False(t, rs.Contains(netip.MustParseAddr("203.0.113.1")), "removals are unmapped")
+
+	rs = NewRouteSum(WithUnmapping(EmbeddingMapped))
+	require.NoError(t, rs.InsertFromString("::ffff:192.0.2.1"))
+	require.NoError(t, rs.InsertFromString("64:ff9b::192.0.2.1"))
+	assert.Equal(
+		t,
+		[]string{"192.0.2.1", "64:ff9b::c000:201"},
+		rs.SummaryStrings(),
+		"only chosen embeddings are unmapped",
+	)
+}
+
+func TestEmbeddedOutput(t *testing.T) {
+	rs := NewRouteSum(WithUnmapping(EmbeddingMapped), WithEmbeddedOutput(EmbeddingMapped))
+	require.NoError(t, rs.InsertFromString("192.0.2.0/25"))
+	require.NoError(t, rs.InsertFromString("::ffff:192.0.2.128/121"))
+	require.NoError(t, rs.InsertFromString("2001:db8::/32"))
+	assert.Equal(t, []string{"::ffff:192.0.2.0/120", "2001:db8::/32"}, rs.SummaryStrings())
+
+	p, ok := rs.Lookup(netip.MustParseAddr("::ffff:192.0.2.1"))
+	assert.True(t, ok)
+	assert.Equal(t, "::ffff:192.0.2.0/120", p.String(), "lookups are given in the form of the IP looked up")
+	p, ok = rs.Lookup(netip.MustParseAddr("192.0.2.1"))
+	assert.True(t, ok)
+	assert.Equal(t, "192.0.2.0/24", p.String(), "lookups of IPv4 addresses are given as IPv4")
+
+	complement := rs.Complement()
+	p, ok = complement.Lookup(netip.MustParseAddr("::ffff:0.0.0.0"))
+	assert.True(t, ok)
+	assert.Equal(t, "::ffff:0.0.0.0/97", p.String(), "set operations keep the output embedding")
+
+	rs = NewRouteSum(WithEmbeddedOutput(EmbeddingNAT64))
+	require.NoError(t, rs.InsertFromString("192.0.2.0/25"))
+	require.NoError(t, rs.InsertFromString("64:ff9b::192.0.2.128/121"))
+	assert.Equal(t, []string{"64:ff9b::c000:200/120"}, rs.SummaryStrings(), "the output embedding is unmapped")
+}
+
+func TestEmbeddedOutputAmongIPv6(t *testing.T) {
+	rs := NewRouteSum(WithUnmapping(EmbeddingCompatible), WithEmbeddedOutput(EmbeddingMapped))
+	require.NoError(t, rs.InsertFromString("::1"))
+	require.NoError(t, rs.InsertFromString("::ffff:192.0.2.0/120"))
+	require.NoError(t, rs.InsertFromString("::fffe:0:0/96"))
+	require.NoError(t, rs.InsertFromString("0.0.0.0/0"))
+	require.NoError(t, rs.InsertFromString("2001:db8::/32"))
+	require.NoError(t, rs.InsertFromString("::/95"))
+
+	assert.Equal(
+		t,
+		[]string{"::/95", "::fffe:0:0/95", "2001:db8::/32"},
+		rs.SummaryStrings(),
+		"IPv4 networks are output in address order, merged with and covered by IPv6 networks",
+	)
+	assert.Equal(
+		t,
+		[]string{"2001:db8::/32", "::fffe:0:0/95", "::/95"},
+		slices.Collect(rs.EachDescending()),
+		"descending output is the reverse",
+	)
+
+	for _, s := range []string{"::1", "::ffff:192.0.2.1", "192.0.2.1"} {
+		ip := netip.MustParseAddr(s)
+		p, ok := rs.Lookup(ip)
+		if assert.True(t, ok, s) {
+			assert.True(t, p.Contains(ip), "%s is in %s", s, p)
+		}
+	}
+	p, _ := rs.Lookup(netip.MustParseAddr("::1"))
+	assert.Equal(t, "::/96", p.String(), "lookups use the embedding of the IP looked up, not the output one")
+}
+
+func TestExplainWithEmbeddings(t *testing.T) {
+	rs := NewRouteSum(WithProvenance(), WithUnmapping(EmbeddingMapped), WithEmbeddedOutput(EmbeddingNAT64))
+	require.NoError(t, rs.InsertFromString("::ffff:192.0.2.0/121"))
+	require.NoError(t, rs.InsertFromString("192.0.2.128/25"))
+
+	explanation, err := rs.Explain()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]PrefixExplanation{
+			{
+				Prefix: netip.MustParsePrefix("64:ff9b::c000:200/120"),
+				Contributors: []Input{
+					{Source: Source{File: "", Line: 0}, Prefix: netip.MustParsePrefix("::ffff:192.0.2.0/121")},
+					{Source: Source{File: "", Line: 0}, Prefix: netip.MustParsePrefix("192.0.2.128/25")},
+				},
+			},
+		},
+		explanation.Prefixes,
+		"inputs are reported as given, and matched to the networks they were unmapped into",
+	)
+}
@@ -1,21 +1,112 @@
 package routesum
 
 import (
+	"errors"
 	"fmt"
 )
 
-// InvalidInputErr represents an error ingesting or validating input
+// ErrInvalidRatio is returned when a ratio isn't between 0 and 1.
+var ErrInvalidRatio = errors.New("ratio must be between 0 and 1")
+
+// ErrNoProvenance is returned when explaining a RouteSum that wasn't made WithProvenance.
+var ErrNoProvenance = errors.New("provenance was not recorded")
+
+// ReasonCode classifies why input was invalid.
+type ReasonCode int
+
+const (
+	// ReasonUnknown is for input that's invalid for an unclassified reason.
+	ReasonUnknown ReasonCode = iota
+	// ReasonInvalidIP is for input that isn't a valid IP.
+	ReasonInvalidIP
+	// ReasonInvalidNetwork is for input that isn't a valid CIDR-formatted network.
+	ReasonInvalidNetwork
+	// ReasonInvalidRange is for input that isn't a valid range of IPs.
+	ReasonInvalidRange
+	// ReasonInvalidMask is for input whose netmask or wildcard mask isn't valid.
+	ReasonInvalidMask
+	// ReasonHostBitsSet is for networks with host bits set, when the RouteSum is made WithStrict.
+	ReasonHostBitsSet
+)
+
+// String returns a short name for the reason, such as invalid-ip.
+func (c ReasonCode) String() string {
+	switch c {
+	case ReasonUnknown:
+		return "unknown"
+	case ReasonInvalidIP:
+		return "invalid-ip"
+	case ReasonInvalidNetwork:
+		return "invalid-network"
+	case ReasonInvalidRange:
+		return "invalid-range"
+	case ReasonInvalidMask:
+		return "invalid-mask"
+	case ReasonHostBitsSet:
+		return "host-bits-set"
+	}
+
+	return fmt.Sprintf("ReasonCode(%d)", int(c))
+}
+
+// InvalidInputErr represents an error ingesting or validating input. Code classifies what was wrong with it, and
+// Reason, if set, describes it. Err is the underlying error, if there is one. Source is where the input came from, if
+// it was inserted with a source.
 type InvalidInputErr struct {
 	InvalidValue string
+	Code         ReasonCode
+	Reason       string
+	Err          error
+	Source       Source
 }
 
 func newInvalidInputErrFromString(str string) *InvalidInputErr {
+	return newInvalidInputErr(str, ReasonUnknown, "")
+}
+
+func newInvalidInputErr(str string, code ReasonCode, reason string) *InvalidInputErr {
 	return &InvalidInputErr{
 		InvalidValue: str,
+		Code:         code,
+		Reason:       reason,
+		Err:          nil,
+		Source:       Source{File: "", Line: 0},
 	}
 }
 
+// wrapInvalidInputErr returns an InvalidInputErr whose reason is an underlying error.
+func wrapInvalidInputErr(str string, code ReasonCode, err error) *InvalidInputErr {
+	e := newInvalidInputErr(str, code, err.Error())
+	e.Err = err
+
+	return e
+}
+
 // Error returns a stringified form of the error.
 func (e *InvalidInputErr) Error() string {
-	return fmt.Sprintf("'%s' was not understood.", e.InvalidValue)
+	msg := fmt.Sprintf("'%s' was not understood.", e.InvalidValue)
+	if e.Reason != "" {
+		msg = fmt.Sprintf("'%s' was not understood: %s.", e.InvalidValue, e.Reason)
+	}
+
+	if e.Source != (Source{File: "", Line: 0}) {
+		return e.Source.String() + ": " + msg
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying error, if there is one.
+func (e *InvalidInputErr) Unwrap() error {
+	return e.Err
+}
+
+// withSource records where the input an error is about came from, if the error is an InvalidInputErr.
+func withSource(err error, source Source) error {
+	var iIErr *InvalidInputErr
+	if errors.As(err, &iIErr) {
+		iIErr.Source = source
+	}
+
+	return err
 }
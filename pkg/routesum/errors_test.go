@@ -2,9 +2,11 @@ package routesum
 
 import (
 	"errors"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInvalidInputErrFromString(t *testing.T) {
@@ -15,3 +17,54 @@ func TestInvalidInputErrFromString(t *testing.T) {
 	assert.True(t, errors.As(e, &iIErr), "unwrapped error identifies as an InvalidInputErr")
 	assert.Equal(t, "'xyz' was not understood.", iIErr.Error(), "unwrapped error stringifies as expected")
 }
+
+func TestInvalidInputErrWithReason(t *testing.T) {
+	e := newInvalidInputErr("xyz", ReasonInvalidIP, "it's not an IP")
+	assert.Equal(t, "'xyz' was not understood: it's not an IP.", e.Error(), "reason is included")
+
+	e.Source = Source{File: "feed", Line: 3}
+	assert.Equal(t, "feed:3: 'xyz' was not understood: it's not an IP.", e.Error(), "source is included")
+}
+
+func TestInvalidInputErrCodes(t *testing.T) {
+	tests := map[string]ReasonCode{
+		"192.0.2":                 ReasonInvalidIP,
+		"fe80::1%eth0":            ReasonInvalidIP,
+		"192.0.2/24":              ReasonInvalidNetwork,
+		"192.0.2.0/33":            ReasonInvalidNetwork,
+		"192.0.2.1-192.0.2":       ReasonInvalidRange,
+		"192.0.2.2-192.0.2.1":     ReasonInvalidRange,
+		"192.0.2.1-2001:db8::1":   ReasonInvalidRange,
+		"192.0.2.0 255.0.255.0":   ReasonInvalidMask,
+		"192.0.2.0 255.255.255":   ReasonInvalidMask,
+		"192.0.2 255.255.255.0":   ReasonInvalidIP,
+		"192.0.2.5/24":            ReasonHostBitsSet,
+		"192.0.2.5 255.255.255.0": ReasonHostBitsSet,
+	}
+	for s, code := range tests {
+		t.Run(s, func(t *testing.T) {
+			rs := NewRouteSum(WithStrict())
+			err := rs.InsertFromStringWithSource(s, Source{File: "feed", Line: 7})
+
+			var iIErr *InvalidInputErr
+			require.ErrorAs(t, err, &iIErr, "error is an InvalidInputErr")
+			assert.Equal(t, code, iIErr.Code, "error has the expected code")
+			assert.Equal(t, s, iIErr.InvalidValue, "error has the value as given")
+			assert.Equal(t, Source{File: "feed", Line: 7}, iIErr.Source, "error has the source")
+		})
+	}
+
+	var iIErr *InvalidInputErr
+	require.ErrorAs(t, NewRouteSum().InsertAddr(netip.Addr{}), &iIErr)
+	assert.Equal(t, ReasonInvalidIP, iIErr.Code, "invalid IPs are rejected")
+	require.ErrorAs(t, NewRouteSum().InsertPrefix(netip.Prefix{}), &iIErr)
+	assert.Equal(t, ReasonInvalidNetwork, iIErr.Code, "invalid networks are rejected")
+	require.ErrorAs(t, NewRouteSum().InsertRange(netip.Addr{}, netip.Addr{}), &iIErr)
+	assert.Equal(t, ReasonInvalidRange, iIErr.Code, "invalid ranges are rejected")
+
+	_, err := parsePrefix("192.0.2.256")
+	_, parseErr := netip.ParseAddr("192.0.2.256")
+	assert.Equal(t, parseErr, errors.Unwrap(err), "the underlying error is wrapped")
+
+	assert.Equal(t, "invalid-range", ReasonInvalidRange.String())
+}
@@ -0,0 +1,114 @@
+package routesum
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
+)
+
+// Source identifies where an input came from, such as a line of a file.
+type Source struct {
+	File string
+	Line int
+}
+
+// String returns the source in file:line form.
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Input is a network or IP inserted into a RouteSum, and where it came from. IPs are single-address networks.
+type Input struct {
+	Source Source
+	Prefix netip.Prefix
+}
+
+// PrefixExplanation lists the inputs that contributed to a summarized network, in the order they were inserted.
+type PrefixExplanation struct {
+	Prefix       netip.Prefix
+	Contributors []Input
+}
+
+// Explanation describes how a summary came to be.
+type Explanation struct {
+	// Prefixes explains each summarized network, in the same order as EachPrefix.
+	Prefixes []PrefixExplanation
+
+	// Covered lists the inputs that contributed nothing because the summary already covered them when they were
+	// inserted, in the order they were inserted.
+	Covered []Input
+}
+
+// provenance records the inputs to a RouteSum.
+type provenance struct {
+	mu          sync.Mutex
+	contributed []Input
+	covered     []Input
+}
+
+func newProvenance() *provenance {
+	return &provenance{
+		mu:          sync.Mutex{},
+		contributed: nil,
+		covered:     nil,
+	}
+}
+
+// record inserts an input's bits into trie, noting whether the trie already covered them.
+func (p *provenance) record(trie *rstrie.RSTrie, ipBits bitslice.BitSlice, input Input) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if trie.Contains(ipBits) {
+		p.covered = append(p.covered, input)
+		return
+	}
+
+	trie.InsertRoute(ipBits)
+	p.contributed = append(p.contributed, input)
+}
+
+// Explain reports, for each summarized network, the inputs that contributed to it, and which inputs were already
+// covered when they were inserted. An input contributes to every summarized network it overlaps, so once networks
+// have been removed, an input may contribute to several. The RouteSum must have been made WithProvenance; RouteSums
+// made by set operations such as Union don't record provenance.
+func (rs *RouteSum) Explain() (Explanation, error) {
+	if rs.provenance == nil {
+		return Explanation{Prefixes: nil, Covered: nil}, ErrNoProvenance
+	}
+
+	rs.provenance.mu.Lock()
+	defer rs.provenance.mu.Unlock()
+
+	prefixes := []PrefixExplanation{}
+	for prefix := range rs.EachPrefix() {
+		prefixes = append(prefixes, PrefixExplanation{Prefix: prefix, Contributors: nil})
+	}
+
+	for _, input := range rs.provenance.contributed {
+		// Inputs are matched to the networks output in the form they're stored in and then output in.
+		stored := rs.render(rs.unmap(input.Prefix))
+
+		// Summarized networks never overlap, so those overlapping the input are adjacent: the last one starting
+		// before it, if that one reaches into it, and those starting within it.
+		i, _ := slices.BinarySearchFunc(prefixes, stored.Addr(), func(pe PrefixExplanation, a netip.Addr) int {
+			return pe.Prefix.Addr().Compare(a)
+		})
+		if i > 0 && prefixes[i-1].Prefix.Overlaps(stored) {
+			i--
+		}
+
+		for ; i < len(prefixes) && prefixes[i].Prefix.Overlaps(stored); i++ {
+			prefixes[i].Contributors = append(prefixes[i].Contributors, input)
+		}
+	}
+
+	return Explanation{
+		Prefixes: prefixes,
+		Covered:  slices.Clone(rs.provenance.covered),
+	}, nil
+}
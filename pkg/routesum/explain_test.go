@@ -0,0 +1,107 @@
+package routesum
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) { //nolint: funlen
+	rs := NewRouteSum(WithProvenance())
+	inputs := []string{
+		"192.0.2.0",
+		"192.0.2.1",
+		"198.51.100.0/24",
+		"192.0.2.1",
+		"198.51.100.7",
+		"2001:db8::/32",
+	}
+	for i, s := range inputs {
+		require.NoError(t, rs.InsertFromStringWithSource(s, Source{File: "in.txt", Line: i + 1}))
+	}
+	require.NoError(t, rs.InsertPrefix(netip.MustParsePrefix("2001:db8::1/120")))
+
+	input := func(line int, prefix string) Input {
+		return Input{Source: Source{File: "in.txt", Line: line}, Prefix: netip.MustParsePrefix(prefix)}
+	}
+	unsourced := Input{Source: Source{File: "", Line: 0}, Prefix: netip.MustParsePrefix("2001:db8::/120")}
+
+	explanation, err := rs.Explain()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		Explanation{
+			Prefixes: []PrefixExplanation{
+				{
+					Prefix:       netip.MustParsePrefix("192.0.2.0/31"),
+					Contributors: []Input{input(1, "192.0.2.0/32"), input(2, "192.0.2.1/32")},
+				},
+				{
+					Prefix:       netip.MustParsePrefix("198.51.100.0/24"),
+					Contributors: []Input{input(3, "198.51.100.0/24")},
+				},
+				{
+					Prefix:       netip.MustParsePrefix("2001:db8::/32"),
+					Contributors: []Input{input(6, "2001:db8::/32")},
+				},
+			},
+			Covered: []Input{input(4, "192.0.2.1/32"), input(5, "198.51.100.7/32"), unsourced},
+		},
+		explanation,
+		"got expected explanation",
+	)
+
+	require.NoError(t, rs.RemoveFromString("198.51.100.128/25"))
+	require.NoError(t, rs.RemoveFromString("198.51.100.0/26"))
+	explanation, err = rs.Explain()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]PrefixExplanation{
+			{
+				Prefix:       netip.MustParsePrefix("192.0.2.0/31"),
+				Contributors: []Input{input(1, "192.0.2.0/32"), input(2, "192.0.2.1/32")},
+			},
+			{
+				Prefix:       netip.MustParsePrefix("198.51.100.64/26"),
+				Contributors: []Input{input(3, "198.51.100.0/24")},
+			},
+			{
+				Prefix:       netip.MustParsePrefix("2001:db8::/32"),
+				Contributors: []Input{input(6, "2001:db8::/32")},
+			},
+		},
+		explanation.Prefixes,
+		"inputs contribute to what remains of them after removal",
+	)
+
+	_, err = NewRouteSum().Explain()
+	assert.ErrorIs(t, err, ErrNoProvenance, "provenance must be recorded to explain")
+
+	_, err = rs.Union(NewRouteSum()).Explain()
+	assert.ErrorIs(t, err, ErrNoProvenance, "set operations don't record provenance")
+}
+
+func TestExplainSplitInput(t *testing.T) {
+	rs := NewRouteSum(WithProvenance())
+	require.NoError(t, rs.InsertFromStringWithSource("192.0.2.0/24", Source{File: "in.txt", Line: 1}))
+	require.NoError(t, rs.InsertFromStringWithSource("192.0.2.0/26", Source{File: "in.txt", Line: 2}))
+	require.NoError(t, rs.RemoveFromString("192.0.2.64/26"))
+
+	explanation, err := rs.Explain()
+	require.NoError(t, err)
+
+	whole := Input{Source: Source{File: "in.txt", Line: 1}, Prefix: netip.MustParsePrefix("192.0.2.0/24")}
+	assert.Equal(
+		t,
+		[]PrefixExplanation{
+			{Prefix: netip.MustParsePrefix("192.0.2.0/26"), Contributors: []Input{whole}},
+			{Prefix: netip.MustParsePrefix("192.0.2.128/25"), Contributors: []Input{whole}},
+		},
+		explanation.Prefixes,
+		"an input split by removal contributes to each part",
+	)
+	assert.Equal(t, "in.txt:2", explanation.Covered[0].Source.String(), "got covered input's source")
+}
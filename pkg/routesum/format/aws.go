@@ -0,0 +1,143 @@
+package format
+
+import (
+	"io"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// AWS's default quotas on the entries of a managed prefix list and the inbound rules of a security group.
+const (
+	awsMaxPrefixListEntries    = 1000
+	awsMaxSecurityGroupEntries = 60
+)
+
+// AWSPrefixList renders a summary as a JSON array of inputs for aws ec2 create-managed-prefix-list --cli-input-json,
+// one per prefix list. As a prefix list holds a single address family, and at most MaxEntries networks, the networks
+// are split into as many lists as they need, named after Name, such as name-v4-1, name-v4-2 and name-v6-1.
+type AWSPrefixList struct {
+	Name       string
+	MaxEntries int
+}
+
+// NewAWSPrefixList returns an AWSPrefixList rendering into lists named after name, within AWS's default quota on the
+// entries of a list.
+func NewAWSPrefixList(name string) AWSPrefixList {
+	return AWSPrefixList{Name: name, MaxEntries: awsMaxPrefixListEntries}
+}
+
+type awsPrefixList struct {
+	PrefixListName string               `json:"PrefixListName"`
+	AddressFamily  string               `json:"AddressFamily"`
+	MaxEntries     int                  `json:"MaxEntries"`
+	Entries        []awsPrefixListEntry `json:"Entries"`
+}
+
+type awsPrefixListEntry struct {
+	Cidr string `json:"Cidr"`
+}
+
+// Write renders the summary rs to w.
+func (f AWSPrefixList) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(cloudName(), f.Name); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	fams, err := chunkFamilies(ipv4, ipv6, f.MaxEntries)
+	if err != nil {
+		return err
+	}
+
+	lists := []awsPrefixList{}
+	for _, fam := range fams {
+		addressFamily := "IPv4"
+		if fam.name == "v6" {
+			addressFamily = "IPv6"
+		}
+
+		for i, chunk := range fam.chunks {
+			entries := make([]awsPrefixListEntry, 0, len(chunk))
+			for _, cidr := range cidrs(chunk) {
+				entries = append(entries, awsPrefixListEntry{Cidr: cidr})
+			}
+
+			lists = append(lists, awsPrefixList{
+				PrefixListName: chunkName(f.Name, fam, i),
+				AddressFamily:  addressFamily,
+				MaxEntries:     len(entries),
+				Entries:        entries,
+			})
+		}
+	}
+
+	return writeJSON(w, lists)
+}
+
+// AWSSecurityGroup renders a summary as a JSON array of inputs for aws ec2 authorize-security-group-ingress
+// --cli-input-json, each allowing all traffic from at most MaxEntries networks. AWS counts each network as an inbound
+// rule, so the networks are split across as many security groups as they need, named after Name, such as name-v4-1,
+// name-v4-2 and name-v6-1.
+type AWSSecurityGroup struct {
+	Name       string
+	MaxEntries int
+}
+
+// NewAWSSecurityGroup returns an AWSSecurityGroup rendering into security groups named after name, within AWS's
+// default quota on the inbound rules of a security group.
+func NewAWSSecurityGroup(name string) AWSSecurityGroup {
+	return AWSSecurityGroup{Name: name, MaxEntries: awsMaxSecurityGroupEntries}
+}
+
+type awsSecurityGroupIngress struct {
+	GroupName     string            `json:"GroupName"`
+	IPPermissions []awsIPPermission `json:"IpPermissions"`
+}
+
+type awsIPPermission struct {
+	IPProtocol string         `json:"IpProtocol"`
+	IPRanges   []awsIPRange   `json:"IpRanges,omitempty"`
+	IPv6Ranges []awsIPv6Range `json:"Ipv6Ranges,omitempty"`
+}
+
+type awsIPRange struct {
+	CidrIP string `json:"CidrIp"`
+}
+
+type awsIPv6Range struct {
+	CidrIPv6 string `json:"CidrIpv6"`
+}
+
+// Write renders the summary rs to w.
+func (f AWSSecurityGroup) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(cloudName(), f.Name); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	fams, err := chunkFamilies(ipv4, ipv6, f.MaxEntries)
+	if err != nil {
+		return err
+	}
+
+	groups := []awsSecurityGroupIngress{}
+	for _, fam := range fams {
+		for i, chunk := range fam.chunks {
+			permission := awsIPPermission{IPProtocol: "-1", IPRanges: nil, IPv6Ranges: nil}
+			for _, cidr := range cidrs(chunk) {
+				if fam.name == "v6" {
+					permission.IPv6Ranges = append(permission.IPv6Ranges, awsIPv6Range{CidrIPv6: cidr})
+				} else {
+					permission.IPRanges = append(permission.IPRanges, awsIPRange{CidrIP: cidr})
+				}
+			}
+
+			groups = append(groups, awsSecurityGroupIngress{
+				GroupName:     chunkName(f.Name, fam, i),
+				IPPermissions: []awsIPPermission{permission},
+			})
+		}
+	}
+
+	return writeJSON(w, groups)
+}
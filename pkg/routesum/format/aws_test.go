@@ -0,0 +1,75 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSPrefixList(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "203.0.113.0/24", "2001:db8::/32")
+
+	f := NewAWSPrefixList("blocklist")
+	f.MaxEntries = 2
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.JSONEq(t, `[
+  {
+    "PrefixListName": "blocklist-v4-1",
+    "AddressFamily": "IPv4",
+    "MaxEntries": 2,
+    "Entries": [{"Cidr": "192.0.2.0/24"}, {"Cidr": "198.51.100.1/32"}]
+  },
+  {
+    "PrefixListName": "blocklist-v4-2",
+    "AddressFamily": "IPv4",
+    "MaxEntries": 1,
+    "Entries": [{"Cidr": "203.0.113.0/24"}]
+  },
+  {
+    "PrefixListName": "blocklist-v6-1",
+    "AddressFamily": "IPv6",
+    "MaxEntries": 1,
+    "Entries": [{"Cidr": "2001:db8::/32"}]
+  }
+]`, b.String(), "lists are chunked per family")
+
+	b.Reset()
+	require.NoError(t, NewAWSPrefixList("blocklist").Write(&b, newRouteSum(t)))
+	assert.Equal(t, "[]\n", b.String())
+
+	f.MaxEntries = 0
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidMaxEntries)
+	assert.ErrorIs(t, NewAWSPrefixList("bad name").Write(&b, rs), ErrInvalidName)
+}
+
+func TestAWSSecurityGroup(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	f := NewAWSSecurityGroup("blocklist")
+	f.MaxEntries = 1
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.JSONEq(t, `[
+  {
+    "GroupName": "blocklist-v4-1",
+    "IpPermissions": [{"IpProtocol": "-1", "IpRanges": [{"CidrIp": "192.0.2.0/24"}]}]
+  },
+  {
+    "GroupName": "blocklist-v4-2",
+    "IpPermissions": [{"IpProtocol": "-1", "IpRanges": [{"CidrIp": "198.51.100.1/32"}]}]
+  },
+  {
+    "GroupName": "blocklist-v6-1",
+    "IpPermissions": [{"IpProtocol": "-1", "Ipv6Ranges": [{"CidrIpv6": "2001:db8::/32"}]}]
+  }
+]`, b.String(), "groups are chunked per family")
+
+	b.Reset()
+	require.NoError(t, NewAWSSecurityGroup("blocklist").Write(&b, rs))
+	assert.Equal(t, 2, strings.Count(b.String(), "GroupName"), "the default quota fits both families")
+}
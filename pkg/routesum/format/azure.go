@@ -0,0 +1,88 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// ErrInvalidPriority is returned when rules can't be given priorities Azure accepts.
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// Azure's limits on the priorities of network security group rules, and the source address prefixes of each.
+const (
+	azureMinPriority        = 100
+	azureMaxPriority        = 4096
+	azureMaxAddressPrefixes = 4000
+)
+
+// AzureNSG renders a summary as a JSON array of Azure network security group inbound rule fragments, each giving the
+// name, priority and sourceAddressPrefixes of a rule, to be completed with the rule's access, protocol and ports. A
+// rule holds a single address family, and at most MaxEntries networks, so the networks are split into as many rules
+// as they need, named after Name, such as name-v4-1, name-v4-2 and name-v6-1. The rules are given consecutive
+// priorities from FirstPriority.
+type AzureNSG struct {
+	Name          string
+	MaxEntries    int
+	FirstPriority int
+}
+
+// NewAzureNSG returns an AzureNSG rendering into rules named after name, within Azure's limit on the source address
+// prefixes of a rule, with priorities from the highest Azure allows.
+func NewAzureNSG(name string) AzureNSG {
+	return AzureNSG{Name: name, MaxEntries: azureMaxAddressPrefixes, FirstPriority: azureMinPriority}
+}
+
+type azureSecurityRule struct {
+	Name       string                      `json:"name"`
+	Properties azureSecurityRuleProperties `json:"properties"`
+}
+
+type azureSecurityRuleProperties struct {
+	Priority              int      `json:"priority"`
+	Direction             string   `json:"direction"`
+	SourceAddressPrefixes []string `json:"sourceAddressPrefixes"`
+}
+
+// Write renders the summary rs to w.
+func (f AzureNSG) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(cloudName(), f.Name); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	fams, err := chunkFamilies(ipv4, ipv6, f.MaxEntries)
+	if err != nil {
+		return err
+	}
+
+	rules := []azureSecurityRule{}
+	for _, fam := range fams {
+		for i, chunk := range fam.chunks {
+			rules = append(rules, azureSecurityRule{
+				Name: chunkName(f.Name, fam, i),
+				Properties: azureSecurityRuleProperties{
+					Priority:              f.FirstPriority + len(rules),
+					Direction:             "Inbound",
+					SourceAddressPrefixes: cidrs(chunk),
+				},
+			})
+		}
+	}
+
+	if lastPriority := f.FirstPriority + max(len(rules)-1, 0); f.FirstPriority < azureMinPriority ||
+		lastPriority > azureMaxPriority {
+		return fmt.Errorf(
+			"%w: rules need priorities %d to %d, but Azure allows %d to %d",
+			ErrInvalidPriority,
+			f.FirstPriority,
+			lastPriority,
+			azureMinPriority,
+			azureMaxPriority,
+		)
+	}
+
+	return writeJSON(w, rules)
+}
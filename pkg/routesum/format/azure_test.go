@@ -0,0 +1,38 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureNSG(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	f := NewAzureNSG("blocklist")
+	f.MaxEntries = 1
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.JSONEq(t, `[
+  {
+    "name": "blocklist-v4-1",
+    "properties": {"priority": 100, "direction": "Inbound", "sourceAddressPrefixes": ["192.0.2.0/24"]}
+  },
+  {
+    "name": "blocklist-v4-2",
+    "properties": {"priority": 101, "direction": "Inbound", "sourceAddressPrefixes": ["198.51.100.1/32"]}
+  },
+  {
+    "name": "blocklist-v6-1",
+    "properties": {"priority": 102, "direction": "Inbound", "sourceAddressPrefixes": ["2001:db8::/32"]}
+  }
+]`, b.String(), "rules are chunked per family, with consecutive priorities")
+
+	f.FirstPriority = 4095
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidPriority, "priorities must stay within Azure's")
+	f.FirstPriority = 99
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidPriority, "priorities must start within Azure's")
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// BIRD renders a summary as BIRD 2 define statements of prefix sets, one per address family, as BIRD's sets can't
+// mix them. A family without any networks isn't defined, as BIRD has no empty prefix set.
+type BIRD struct {
+	// IPv4Set and IPv6Set are the names of the constants defined for each address family.
+	IPv4Set string
+	IPv6Set string
+	// IPv4Bounds and IPv6Bounds widen the entries for each address family.
+	IPv4Bounds Bounds
+	IPv6Bounds Bounds
+}
+
+// NewBIRD returns a BIRD rendering into sets named after name, such as name_v4 and name_v6.
+func NewBIRD(name string) BIRD {
+	return BIRD{
+		IPv4Set:    name + "_v4",
+		IPv6Set:    name + "_v6",
+		IPv4Bounds: Bounds{GE: 0, LE: 0},
+		IPv6Bounds: Bounds{GE: 0, LE: 0},
+	}
+}
+
+// Write renders the summary rs to w.
+func (f BIRD) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(identifier(), f.IPv4Set, f.IPv6Set); err != nil {
+		return err
+	}
+	if err := f.IPv4Bounds.check(32); err != nil {
+		return err
+	}
+	if err := f.IPv6Bounds.check(128); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	sets := []struct {
+		name     string
+		bounds   Bounds
+		prefixes []netip.Prefix
+	}{
+		{name: f.IPv4Set, bounds: f.IPv4Bounds, prefixes: ipv4},
+		{name: f.IPv6Set, bounds: f.IPv6Bounds, prefixes: ipv6},
+	}
+
+	var b strings.Builder
+	for _, set := range sets {
+		if len(set.prefixes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "define %s = [\n", set.name)
+		for i, p := range set.prefixes {
+			b.WriteString("\t" + p.String())
+			if shortest, longest := set.bounds.lengths(p); longest > p.Bits() {
+				fmt.Fprintf(&b, "{%d,%d}", shortest, longest)
+			}
+			if i < len(set.prefixes)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("];\n")
+	}
+
+	return write(w, b.String())
+}
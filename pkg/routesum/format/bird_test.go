@@ -0,0 +1,34 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBIRD(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	f := NewBIRD("bogons")
+	f.IPv4Bounds = Bounds{GE: 0, LE: 28}
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `define bogons_v4 = [
+	192.0.2.0/24{24,28},
+	198.51.100.1/32
+];
+define bogons_v6 = [
+	2001:db8::/32
+];
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, NewBIRD("bogons").Write(&b, newRouteSum(t, "2001:db8::/32")))
+	assert.Equal(t, "define bogons_v6 = [\n\t2001:db8::/32\n];\n", b.String(), "empty families aren't defined")
+
+	err := NewBIRD("bogon-list").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
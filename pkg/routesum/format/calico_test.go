@@ -0,0 +1,40 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalicoNetworkSet(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "2001:db8::/32")
+
+	f := NewCalicoNetworkSet("blocklist")
+	f.Labels = map[string]string{"role": "blocklist", "example.com/feed": "true"}
+	f.Exclude = newRouteSum(t, "192.0.2.0/25")
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `apiVersion: projectcalico.org/v3
+kind: GlobalNetworkSet
+metadata:
+  name: blocklist
+  labels:
+    "example.com/feed": "true"
+    "role": "blocklist"
+spec:
+  nets:
+    - "192.0.2.128/25"
+    - "2001:db8::/32"
+`, b.String(), "exclusions are left out")
+
+	b.Reset()
+	require.NoError(t, NewCalicoNetworkSet("empty").Write(&b, newRouteSum(t)))
+	assert.Equal(t, "apiVersion: projectcalico.org/v3\nkind: GlobalNetworkSet\nmetadata:\n  name: empty\n"+
+		"spec:\n  nets: []\n", b.String())
+
+	f.Labels = map[string]string{"role": "a b"}
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidLabel)
+}
@@ -0,0 +1,60 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"slices"
+)
+
+// family is the networks of one address family of a summary, split into chunks for cloud rules or lists.
+type family struct {
+	// name is v4 or v6, for naming the rules or lists.
+	name   string
+	chunks [][]netip.Prefix
+}
+
+// chunkFamilies splits each address family's networks in prefixes into chunks of at most maxEntries networks.
+func chunkFamilies(ipv4, ipv6 []netip.Prefix, maxEntries int) ([]family, error) {
+	if maxEntries < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMaxEntries, maxEntries)
+	}
+
+	return []family{
+		{name: "v4", chunks: slices.Collect(slices.Chunk(ipv4, maxEntries))},
+		{name: "v6", chunks: slices.Collect(slices.Chunk(ipv6, maxEntries))},
+	}, nil
+}
+
+// chunkName returns the name of the i-th chunk, counting from 0, of an address family's rules or lists.
+func chunkName(name string, f family, i int) string {
+	return fmt.Sprintf("%s-%s-%d", name, f.name, i+1)
+}
+
+// cidrs returns the networks as strings in CIDR notation.
+func cidrs(prefixes []netip.Prefix) []string {
+	s := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		s = append(s, p.String())
+	}
+
+	return s
+}
+
+// cloudName matches names cloud providers accept for rules and lists, before a chunk's suffix is added.
+func cloudName() *regexp.Regexp {
+	return regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
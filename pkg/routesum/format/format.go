@@ -0,0 +1,64 @@
+// Package format renders summaries in the configuration syntax of the systems that consume them, such as firewalls.
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// ErrInvalidName is returned when a name given to a Format can't be used in its syntax.
+var ErrInvalidName = errors.New("invalid name")
+
+// ErrInvalidMaxEntries is returned when a limit on the entries of a set, rule or list is less than one.
+var ErrInvalidMaxEntries = errors.New("invalid maximum entries")
+
+// Format renders a summary.
+type Format interface {
+	// Write renders the summary rs to w.
+	Write(w io.Writer, rs *routesum.RouteSum) error
+}
+
+// families splits a summary's networks by address family, in ascending order. IPv4 networks output embedded in IPv6
+// are IPv6 networks here.
+func families(rs *routesum.RouteSum) ([]netip.Prefix, []netip.Prefix) {
+	var ipv4, ipv6 []netip.Prefix
+	for p := range rs.EachPrefix() {
+		if p.Addr().Is4() {
+			ipv4 = append(ipv4, p)
+		} else {
+			ipv6 = append(ipv6, p)
+		}
+	}
+
+	return ipv4, ipv6
+}
+
+// checkNames returns an error naming the first of names that doesn't match valid.
+func checkNames(valid *regexp.Regexp, names ...string) error {
+	for _, name := range names {
+		if !valid.MatchString(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidName, name)
+		}
+	}
+
+	return nil
+}
+
+// identifier matches names usable as identifiers without quoting, as nft and BIRD require.
+func identifier() *regexp.Regexp {
+	return regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+}
+
+// write writes s to w.
+func write(w io.Writer, s string) error {
+	if _, err := io.WriteString(w, s); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+	"github.com/stretchr/testify/require"
+)
+
+// newRouteSum returns a summary of the given networks and IPs.
+func newRouteSum(t *testing.T, inputs ...string) *routesum.RouteSum {
+	t.Helper()
+
+	rs := routesum.NewRouteSum()
+	for _, s := range inputs {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	return rs
+}
@@ -0,0 +1,65 @@
+package format
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// gcpMaxSourceRanges is GCP's limit on the source ranges of a firewall rule.
+const gcpMaxSourceRanges = 5000
+
+// GCPFirewall renders a summary as a JSON array of GCP ingress firewall rule fragments, each giving the name and
+// sourceRanges of a rule, to be completed with the rule's network and what it allows or denies. A rule holds a single
+// address family, and at most MaxEntries networks, so the networks are split into as many rules as they need, named
+// after Name, such as name-v4-1, name-v4-2 and name-v6-1.
+type GCPFirewall struct {
+	Name       string
+	MaxEntries int
+}
+
+// NewGCPFirewall returns a GCPFirewall rendering into rules named after name, within GCP's limit on the source ranges
+// of a rule.
+func NewGCPFirewall(name string) GCPFirewall {
+	return GCPFirewall{Name: name, MaxEntries: gcpMaxSourceRanges}
+}
+
+type gcpFirewall struct {
+	Name         string   `json:"name"`
+	Direction    string   `json:"direction"`
+	SourceRanges []string `json:"sourceRanges"`
+}
+
+// Write renders the summary rs to w.
+func (f GCPFirewall) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(gcpName(), f.Name); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	fams, err := chunkFamilies(ipv4, ipv6, f.MaxEntries)
+	if err != nil {
+		return err
+	}
+
+	rules := []gcpFirewall{}
+	for _, fam := range fams {
+		for i, chunk := range fam.chunks {
+			// The chunk's suffix can make the name too long.
+			name := chunkName(f.Name, fam, i)
+			if err := checkNames(gcpName(), name); err != nil {
+				return err
+			}
+
+			rules = append(rules, gcpFirewall{Name: name, Direction: "INGRESS", SourceRanges: cidrs(chunk)})
+		}
+	}
+
+	return writeJSON(w, rules)
+}
+
+// gcpName matches names GCP accepts for resources.
+func gcpName() *regexp.Regexp {
+	return regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+}
@@ -0,0 +1,28 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCPFirewall(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	f := NewGCPFirewall("blocklist")
+	f.MaxEntries = 1
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.JSONEq(t, `[
+  {"name": "blocklist-v4-1", "direction": "INGRESS", "sourceRanges": ["192.0.2.0/24"]},
+  {"name": "blocklist-v4-2", "direction": "INGRESS", "sourceRanges": ["198.51.100.1/32"]},
+  {"name": "blocklist-v6-1", "direction": "INGRESS", "sourceRanges": ["2001:db8::/32"]}
+]`, b.String(), "rules are chunked per family")
+
+	assert.ErrorIs(t, NewGCPFirewall("Blocklist").Write(&b, rs), ErrInvalidName, "GCP names are lowercase")
+	long := NewGCPFirewall(strings.Repeat("a", 60))
+	assert.ErrorIs(t, long.Write(&b, rs), ErrInvalidName, "names must fit with their suffix")
+}
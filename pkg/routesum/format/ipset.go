@@ -0,0 +1,109 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// ipsetMaxNameLen is the longest name ipset accepts.
+const ipsetMaxNameLen = 31
+
+// ipsetDefaultMaxElem is ipset's default maxelem.
+const ipsetDefaultMaxElem = 65536
+
+// ErrTooManyNetworks is returned when a summary has more networks than a set can hold.
+var ErrTooManyNetworks = errors.New("too many networks")
+
+// IPSet renders a summary as a script for ipset restore. For each address family, the script fills a temporary
+// hash:net set with the summary's networks, then swaps it with the set in use, which is created if it doesn't exist,
+// and destroys the temporary set. The set in use is never seen empty or half-filled.
+type IPSet struct {
+	// IPv4Set and IPv6Set are the names of the sets for each address family. Their temporary sets have the same names
+	// followed by _tmp.
+	IPv4Set string
+	IPv6Set string
+	// MaxElem is the maxelem every set is created with. ipset only lets an existing set be created again with the same
+	// parameters, so it must stay the same from one script to the next.
+	MaxElem int
+}
+
+// NewIPSet returns an IPSet rendering into sets named after name, such as name_v4 and name_v6, with ipset's default
+// maxelem.
+func NewIPSet(name string) IPSet {
+	return IPSet{
+		IPv4Set: name + "_v4",
+		IPv6Set: name + "_v6",
+		MaxElem: ipsetDefaultMaxElem,
+	}
+}
+
+// Write renders the summary rs to w.
+func (f IPSet) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(ipsetName(), f.IPv4Set+"_tmp", f.IPv6Set+"_tmp"); err != nil {
+		return err
+	}
+
+	if f.MaxElem < 1 {
+		return fmt.Errorf("%w: maxelem %d", ErrInvalidMaxEntries, f.MaxElem)
+	}
+
+	ipv4, ipv6 := families(rs)
+	sets := []struct {
+		name, family string
+		prefixes     []netip.Prefix
+	}{
+		{name: f.IPv4Set, family: "inet", prefixes: ipv4},
+		{name: f.IPv6Set, family: "inet6", prefixes: ipv6},
+	}
+
+	var b strings.Builder
+	for _, set := range sets {
+		prefixes := ipsetPrefixes(set.prefixes)
+		if len(prefixes) > f.MaxElem {
+			return fmt.Errorf(
+				"%w: %s needs %d, but maxelem is %d",
+				ErrTooManyNetworks,
+				set.name,
+				len(prefixes),
+				f.MaxElem,
+			)
+		}
+		tmpName := set.name + "_tmp"
+
+		fmt.Fprintf(&b, "create %s hash:net family %s maxelem %d -exist\n", set.name, set.family, f.MaxElem)
+		fmt.Fprintf(&b, "create %s hash:net family %s maxelem %d -exist\n", tmpName, set.family, f.MaxElem)
+		fmt.Fprintf(&b, "flush %s\n", tmpName)
+		for _, p := range prefixes {
+			fmt.Fprintf(&b, "add %s %s\n", tmpName, routesum.PrefixString(p))
+		}
+		fmt.Fprintf(&b, "swap %s %s\n", tmpName, set.name)
+		fmt.Fprintf(&b, "destroy %s\n", tmpName)
+	}
+
+	return write(w, b.String())
+}
+
+// ipsetPrefixes returns networks as hash:net sets can hold them. Such sets can't hold a /0, so it's split into two
+// /1s.
+func ipsetPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	if len(prefixes) != 1 || prefixes[0].Bits() != 0 {
+		return prefixes
+	}
+
+	upperBytes := prefixes[0].Addr().AsSlice()
+	upperBytes[0] = 0x80
+	upper, _ := netip.AddrFromSlice(upperBytes)
+
+	return []netip.Prefix{netip.PrefixFrom(prefixes[0].Addr(), 1), netip.PrefixFrom(upper, 1)}
+}
+
+// ipsetName matches names ipset accepts.
+func ipsetName() *regexp.Regexp {
+	return regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,` + fmt.Sprint(ipsetMaxNameLen-1) + `}$`)
+}
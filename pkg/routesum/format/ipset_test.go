@@ -0,0 +1,72 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPSet(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1")
+
+	var b strings.Builder
+	require.NoError(t, NewIPSet("blocklist").Write(&b, rs))
+	assert.Equal(t, `create blocklist_v4 hash:net family inet maxelem 65536 -exist
+create blocklist_v4_tmp hash:net family inet maxelem 65536 -exist
+flush blocklist_v4_tmp
+add blocklist_v4_tmp 192.0.2.0/24
+add blocklist_v4_tmp 198.51.100.1
+swap blocklist_v4_tmp blocklist_v4
+destroy blocklist_v4_tmp
+create blocklist_v6 hash:net family inet6 maxelem 65536 -exist
+create blocklist_v6_tmp hash:net family inet6 maxelem 65536 -exist
+flush blocklist_v6_tmp
+swap blocklist_v6_tmp blocklist_v6
+destroy blocklist_v6_tmp
+`, b.String(), "both families are replaced, even if empty")
+
+	b.Reset()
+	require.NoError(t, NewIPSet("all").Write(&b, newRouteSum(t, "0.0.0.0/0", "::/0")))
+	assert.Contains(t, b.String(), "add all_v4_tmp 0.0.0.0/1\nadd all_v4_tmp 128.0.0.0/1\n", "/0 is split")
+	assert.Contains(t, b.String(), "add all_v6_tmp ::/1\nadd all_v6_tmp 8000::/1\n", "/0 is split")
+
+	// Every other IP, so that none are summarized.
+	big := newRouteSum(t)
+	for i := range 65537 {
+		require.NoError(t, big.InsertFromString(fmt.Sprintf("10.%d.%d.%d", i>>15, i>>7&0xff, i<<1&0xff)))
+	}
+	b.Reset()
+	assert.ErrorIs(t, NewIPSet("big").Write(&b, big), ErrTooManyNetworks, "sets can't outgrow maxelem")
+
+	// A script regenerated for a larger summary must create the sets as before, or ipset restore fails on them.
+	f := NewIPSet("grown")
+	f.MaxElem = 131072
+	var small, large strings.Builder
+	require.NoError(t, f.Write(&small, rs))
+	require.NoError(t, f.Write(&large, big))
+	assert.Equal(t, createLines(small.String()), createLines(large.String()), "sets are created the same way")
+	assert.Contains(t, large.String(), "create grown_v4 hash:net family inet maxelem 131072 -exist\n")
+
+	f.MaxElem = 0
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidMaxEntries)
+
+	err := NewIPSet("a name that is far too long for ipset").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	err = NewIPSet("bad name").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+// createLines returns the lines of an ipset script that create sets.
+func createLines(script string) []string {
+	var lines []string
+	for line := range strings.Lines(script) {
+		if strings.HasPrefix(line, "create ") {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
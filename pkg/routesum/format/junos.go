@@ -0,0 +1,73 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// Junos renders a summary as Junos set commands replacing a policy-options list holding both address families. As
+// Junos prefix lists can't have bounds, a route-filter-list is used instead when any are given. The list is deleted
+// before being set, which takes effect atomically on commit.
+type Junos struct {
+	// List is the name of the prefix list or route filter list.
+	List string
+	// IPv4Bounds and IPv6Bounds widen the entries for each address family.
+	IPv4Bounds Bounds
+	IPv6Bounds Bounds
+}
+
+// NewJunos returns a Junos rendering into a list named name.
+func NewJunos(name string) Junos {
+	return Junos{
+		List:       name,
+		IPv4Bounds: Bounds{GE: 0, LE: 0},
+		IPv6Bounds: Bounds{GE: 0, LE: 0},
+	}
+}
+
+// Write renders the summary rs to w.
+func (f Junos) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(listName(), f.List); err != nil {
+		return err
+	}
+	if err := f.IPv4Bounds.check(32); err != nil {
+		return err
+	}
+	if err := f.IPv6Bounds.check(128); err != nil {
+		return err
+	}
+
+	listType := "prefix-list"
+	if f.IPv4Bounds != (Bounds{GE: 0, LE: 0}) || f.IPv6Bounds != (Bounds{GE: 0, LE: 0}) {
+		listType = "route-filter-list"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "delete policy-options %s %s\n", listType, f.List)
+	for p := range rs.EachPrefix() {
+		fmt.Fprintf(&b, "set policy-options %s %s %s", listType, f.List, p.String())
+
+		if listType == "route-filter-list" {
+			bounds := f.IPv4Bounds
+			if p.Addr().Is6() {
+				bounds = f.IPv6Bounds
+			}
+
+			switch shortest, longest := bounds.lengths(p); {
+			case longest == p.Bits():
+				b.WriteString(" exact")
+			case shortest == p.Bits():
+				fmt.Fprintf(&b, " upto /%d", longest)
+			default:
+				fmt.Fprintf(&b, " prefix-length-range /%d-/%d", shortest, longest)
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	return write(w, b.String())
+}
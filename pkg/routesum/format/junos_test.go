@@ -0,0 +1,38 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJunos(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	var b strings.Builder
+	require.NoError(t, NewJunos("bogons").Write(&b, rs))
+	assert.Equal(t, `delete policy-options prefix-list bogons
+set policy-options prefix-list bogons 192.0.2.0/24
+set policy-options prefix-list bogons 198.51.100.1/32
+set policy-options prefix-list bogons 2001:db8::/32
+`, b.String())
+
+	f := NewJunos("bogons")
+	f.IPv4Bounds = Bounds{GE: 0, LE: 28}
+	f.IPv6Bounds = Bounds{GE: 48, LE: 64}
+	b.Reset()
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `delete policy-options route-filter-list bogons
+set policy-options route-filter-list bogons 192.0.2.0/24 upto /28
+set policy-options route-filter-list bogons 198.51.100.1/32 exact
+set policy-options route-filter-list bogons 2001:db8::/32 prefix-length-range /48-/64
+`, b.String(), "bounds need a route filter list")
+
+	err := NewJunos("bad name").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	f.IPv6Bounds = Bounds{GE: 0, LE: 129}
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidBounds)
+}
@@ -0,0 +1,101 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// ErrEmptySummary is returned when a summary has no networks, and the Format can't usefully render it.
+var ErrEmptySummary = errors.New("empty summary")
+
+// K8sNetworkPolicy renders a summary as a Kubernetes NetworkPolicy blocking egress from all pods in a namespace to the
+// summary's networks: egress is allowed to ipBlocks of 0.0.0.0/0 and ::/0, except for the summary's networks of each
+// address family. An address family the summary wholly covers has no ipBlock, so a summary of every address allows no
+// egress at all. An empty summary is an error, as a policy blocking nothing is more likely a mistake than intended.
+type K8sNetworkPolicy struct {
+	Name string
+	// Namespace is the namespace of the policy, or empty for the namespace it's applied in.
+	Namespace string
+	// Exclude, if not nil, is left out of the summary's networks, so that egress to it isn't blocked.
+	Exclude *routesum.RouteSum
+}
+
+// NewK8sNetworkPolicy returns a K8sNetworkPolicy rendering a policy named name.
+func NewK8sNetworkPolicy(name string) K8sNetworkPolicy {
+	return K8sNetworkPolicy{Name: name, Namespace: "", Exclude: nil}
+}
+
+// Write renders the summary rs to w.
+func (f K8sNetworkPolicy) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(k8sName(), f.Name); err != nil {
+		return err
+	}
+	if f.Namespace != "" {
+		if err := checkNames(k8sNamespace(), f.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if f.Exclude != nil {
+		rs = rs.Difference(f.Exclude)
+	}
+	ipv4, ipv6 := families(rs)
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		return fmt.Errorf("%w: a NetworkPolicy would block no egress", ErrEmptySummary)
+	}
+
+	var b strings.Builder
+	b.WriteString("apiVersion: networking.k8s.io/v1\nkind: NetworkPolicy\nmetadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", f.Name)
+	if f.Namespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", f.Namespace)
+	}
+	b.WriteString("spec:\n  podSelector: {}\n  policyTypes:\n    - Egress\n")
+
+	blocks := []struct {
+		all    netip.Prefix
+		except []netip.Prefix
+	}{
+		{all: netip.PrefixFrom(netip.IPv4Unspecified(), 0), except: ipv4},
+		{all: netip.PrefixFrom(netip.IPv6Unspecified(), 0), except: ipv6},
+	}
+	var ipBlocks strings.Builder
+	for _, block := range blocks {
+		// Kubernetes requires except entries to be strictly within the ipBlock's cidr.
+		if len(block.except) == 1 && block.except[0] == block.all {
+			continue
+		}
+
+		fmt.Fprintf(&ipBlocks, "        - ipBlock:\n            cidr: %q\n", block.all.String())
+		if len(block.except) > 0 {
+			ipBlocks.WriteString("            except:\n")
+			for _, p := range block.except {
+				fmt.Fprintf(&ipBlocks, "              - %q\n", p.String())
+			}
+		}
+	}
+
+	if ipBlocks.Len() == 0 {
+		b.WriteString("  egress: []\n")
+	} else {
+		b.WriteString("  egress:\n    - to:\n" + ipBlocks.String())
+	}
+
+	return write(w, b.String())
+}
+
+// k8sName matches the DNS subdomain names Kubernetes accepts for most resources.
+func k8sName() *regexp.Regexp {
+	return regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+}
+
+// k8sNamespace matches the DNS label names Kubernetes accepts for namespaces.
+func k8sNamespace() *regexp.Regexp {
+	return regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+}
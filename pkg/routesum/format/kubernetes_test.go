@@ -0,0 +1,75 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestK8sNetworkPolicy(t *testing.T) { //nolint: funlen
+	rs := newRouteSum(t, "10.0.0.0/8", "192.0.2.0/24", "2001:db8::/32", "2001:dba::/32")
+
+	f := NewK8sNetworkPolicy("block-egress")
+	f.Namespace = "apps"
+	f.Exclude = newRouteSum(t, "10.128.0.0/9", "2001:dba::/31")
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: block-egress
+  namespace: apps
+spec:
+  podSelector: {}
+  policyTypes:
+    - Egress
+  egress:
+    - to:
+        - ipBlock:
+            cidr: "0.0.0.0/0"
+            except:
+              - "10.0.0.0/9"
+              - "192.0.2.0/24"
+        - ipBlock:
+            cidr: "::/0"
+            except:
+              - "2001:db8::/32"
+`, b.String(), "the summary's networks, less exclusions, are blocked")
+
+	b.Reset()
+	require.NoError(t, NewK8sNetworkPolicy("block-egress").Write(&b, newRouteSum(t, "0.0.0.0/0", "2001:db8::/32")))
+	assert.Contains(t, b.String(), `  egress:
+    - to:
+        - ipBlock:
+            cidr: "::/0"
+            except:
+              - "2001:db8::/32"
+`, "a wholly blocked address family has no ipBlock")
+
+	b.Reset()
+	require.NoError(t, NewK8sNetworkPolicy("block-egress").Write(&b, newRouteSum(t, "0.0.0.0/0", "::/0")))
+	assert.Contains(t, b.String(), "  egress: []\n", "a summary of everything allows no egress")
+
+	embedded := routesum.NewRouteSum(routesum.WithEmbeddedOutput(routesum.EmbeddingMapped))
+	require.NoError(t, embedded.InsertFromString("192.0.2.0/24"))
+	b.Reset()
+	require.NoError(t, NewK8sNetworkPolicy("block-egress").Write(&b, embedded))
+	assert.Contains(t, b.String(), `        - ipBlock:
+            cidr: "::/0"
+            except:
+              - "::ffff:192.0.2.0/120"
+`, "IPv4 networks output embedded in IPv6 are blocked as IPv6 networks")
+
+	err := NewK8sNetworkPolicy("block-egress").Write(&b, newRouteSum(t))
+	assert.ErrorIs(t, err, ErrEmptySummary, "an empty summary would block nothing")
+	f.Exclude = newRouteSum(t, "0.0.0.0/0", "::/0")
+	assert.ErrorIs(t, f.Write(&b, rs), ErrEmptySummary, "so would one wholly excluded")
+
+	assert.ErrorIs(t, NewK8sNetworkPolicy("Bad_Name").Write(&b, rs), ErrInvalidName)
+	f.Namespace = "apps.example"
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidName, "namespaces are DNS labels")
+}
@@ -0,0 +1,80 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// NFT renders a summary as an nftables script for nft -f. The script declares an ipv4_addr set and an ipv6_addr set,
+// both with flags interval, in a table, then flushes them and adds the summary's networks to them. nft applies a
+// script as one transaction, so the sets are never seen empty or half-filled.
+type NFT struct {
+	// Family is the table's family, such as inet.
+	Family string
+	// Table is the name of the table holding the sets.
+	Table string
+	// IPv4Set and IPv6Set are the names of the sets for each address family.
+	IPv4Set string
+	IPv6Set string
+}
+
+// NewNFT returns an NFT rendering into sets named after name, such as name_v4 and name_v6, in the inet filter table.
+func NewNFT(name string) NFT {
+	return NFT{
+		Family:  "inet",
+		Table:   "filter",
+		IPv4Set: name + "_v4",
+		IPv6Set: name + "_v6",
+	}
+}
+
+// Write renders the summary rs to w.
+func (f NFT) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(identifier(), f.Family, f.Table, f.IPv4Set, f.IPv6Set); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	sets := []struct {
+		name, addrType string
+		prefixes       []netip.Prefix
+	}{
+		{name: f.IPv4Set, addrType: "ipv4_addr", prefixes: ipv4},
+		{name: f.IPv6Set, addrType: "ipv6_addr", prefixes: ipv6},
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/usr/sbin/nft -f\n\n")
+	fmt.Fprintf(&b, "table %s %s {\n", f.Family, f.Table)
+	for _, set := range sets {
+		fmt.Fprintf(&b, "\tset %s {\n\t\ttype %s\n\t\tflags interval\n\t}\n", set.name, set.addrType)
+	}
+	b.WriteString("}\n\n")
+
+	for _, set := range sets {
+		fmt.Fprintf(&b, "flush set %s %s %s\n", f.Family, f.Table, set.name)
+	}
+
+	for _, set := range sets {
+		// An empty element list isn't valid, and a flushed set is already empty.
+		if len(set.prefixes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "add element %s %s %s {\n", f.Family, f.Table, set.name)
+		for i, p := range set.prefixes {
+			separator := ","
+			if i == len(set.prefixes)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(&b, "\t%s%s\n", routesum.PrefixString(p), separator)
+		}
+		b.WriteString("}\n")
+	}
+
+	return write(w, b.String())
+}
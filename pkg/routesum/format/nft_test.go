@@ -0,0 +1,65 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNFT(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	var b strings.Builder
+	require.NoError(t, NewNFT("blocklist").Write(&b, rs))
+	assert.Equal(t, `#!/usr/sbin/nft -f
+
+table inet filter {
+	set blocklist_v4 {
+		type ipv4_addr
+		flags interval
+	}
+	set blocklist_v6 {
+		type ipv6_addr
+		flags interval
+	}
+}
+
+flush set inet filter blocklist_v4
+flush set inet filter blocklist_v6
+add element inet filter blocklist_v4 {
+	192.0.2.0/24,
+	198.51.100.1
+}
+add element inet filter blocklist_v6 {
+	2001:db8::/32
+}
+`, b.String())
+
+	b.Reset()
+	f := NFT{Family: "ip", Table: "fw", IPv4Set: "deny4", IPv6Set: "deny6"}
+	require.NoError(t, f.Write(&b, newRouteSum(t, "192.0.2.1")))
+	assert.Equal(t, `#!/usr/sbin/nft -f
+
+table ip fw {
+	set deny4 {
+		type ipv4_addr
+		flags interval
+	}
+	set deny6 {
+		type ipv6_addr
+		flags interval
+	}
+}
+
+flush set ip fw deny4
+flush set ip fw deny6
+add element ip fw deny4 {
+	192.0.2.1
+}
+`, b.String(), "empty sets are flushed but not added to")
+
+	err := NewNFT("bad name; flush ruleset").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
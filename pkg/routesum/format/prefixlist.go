@@ -0,0 +1,181 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum"
+)
+
+// ErrInvalidBounds is returned when prefix length bounds can't be used.
+var ErrInvalidBounds = errors.New("invalid prefix length bounds")
+
+// ciscoFirstSeq and ciscoSeqStep are the sequence numbers Cisco IOS gives prefix list entries by default.
+const (
+	ciscoFirstSeq = 5
+	ciscoSeqStep  = 5
+)
+
+// Bounds widens each entry of a prefix list to also match longer networks within it, as the ge and le keywords of
+// Cisco prefix lists do. GE is the shortest and LE the longest prefix length matched, where either is 0 if unset.
+// Bounds that don't exceed an entry's own prefix length don't apply to it, so, for example, an LE of 24 widens a /16
+// to match /16 to /24 networks, but leaves a /28 matching only itself.
+type Bounds struct {
+	GE int
+	LE int
+}
+
+// check returns an error if the bounds are invalid for an address family of bitLen bits.
+func (b Bounds) check(bitLen int) error {
+	if b.GE < 0 || b.GE > bitLen || b.LE < 0 || b.LE > bitLen || (b.GE > 0 && b.LE > 0 && b.GE > b.LE) {
+		return fmt.Errorf("%w: ge %d le %d for %d-bit addresses", ErrInvalidBounds, b.GE, b.LE, bitLen)
+	}
+
+	return nil
+}
+
+// lengths returns the shortest and longest prefix lengths an entry for p matches.
+func (b Bounds) lengths(p netip.Prefix) (int, int) {
+	shortest, longest := p.Bits(), p.Bits()
+	if b.GE > p.Bits() {
+		shortest = b.GE
+		longest = p.Addr().BitLen()
+	}
+	if b.LE > p.Bits() {
+		longest = b.LE
+	}
+
+	return shortest, longest
+}
+
+// ciscoBounds returns the ge and le keywords for an entry for p in a Cisco-style prefix list, if any.
+func (b Bounds) ciscoBounds(p netip.Prefix) string {
+	shortest, longest := b.lengths(p)
+
+	var s string
+	if shortest > p.Bits() {
+		s += fmt.Sprintf(" ge %d", shortest)
+	}
+	if longest > p.Bits() {
+		s += fmt.Sprintf(" le %d", longest)
+	}
+
+	return s
+}
+
+// Cisco renders a summary as Cisco IOS ip prefix-list and ipv6 prefix-list commands replacing each list: the list is
+// removed, then each network is permitted with sequence numbers 5, 10, 15 and so on, and a final entry denies
+// everything else, so that the list exists even when there's nothing to permit. Replacing a list isn't atomic: while
+// the commands are applied, the list is missing or partial, and policies referencing it may match differently until
+// it's complete.
+type Cisco struct {
+	// IPv4List and IPv6List are the names of the prefix lists for each address family.
+	IPv4List string
+	IPv6List string
+	// IPv4Bounds and IPv6Bounds widen the entries for each address family.
+	IPv4Bounds Bounds
+	IPv6Bounds Bounds
+}
+
+// NewCisco returns a Cisco rendering into prefix lists named after name, such as name_v4 and name_v6.
+func NewCisco(name string) Cisco {
+	return Cisco{
+		IPv4List:   name + "_v4",
+		IPv6List:   name + "_v6",
+		IPv4Bounds: Bounds{GE: 0, LE: 0},
+		IPv6Bounds: Bounds{GE: 0, LE: 0},
+	}
+}
+
+// Write renders the summary rs to w.
+func (f Cisco) Write(w io.Writer, rs *routesum.RouteSum) error {
+	if err := checkNames(listName(), f.IPv4List, f.IPv6List); err != nil {
+		return err
+	}
+	if err := f.IPv4Bounds.check(32); err != nil {
+		return err
+	}
+	if err := f.IPv6Bounds.check(128); err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := families(rs)
+	lists := []struct {
+		command, name string
+		bounds        Bounds
+		prefixes      []netip.Prefix
+		all           netip.Prefix
+	}{
+		{
+			command:  "ip prefix-list",
+			name:     f.IPv4List,
+			bounds:   f.IPv4Bounds,
+			prefixes: ipv4,
+			all:      netip.PrefixFrom(netip.IPv4Unspecified(), 0),
+		},
+		{
+			command:  "ipv6 prefix-list",
+			name:     f.IPv6List,
+			bounds:   f.IPv6Bounds,
+			prefixes: ipv6,
+			all:      netip.PrefixFrom(netip.IPv6Unspecified(), 0),
+		},
+	}
+
+	var b strings.Builder
+	for _, list := range lists {
+		fmt.Fprintf(&b, "no %s %s\n", list.command, list.name)
+		for i, p := range list.prefixes {
+			fmt.Fprintf(
+				&b,
+				"%s %s seq %d permit %s%s\n",
+				list.command,
+				list.name,
+				ciscoFirstSeq+i*ciscoSeqStep,
+				p.String(),
+				list.bounds.ciscoBounds(p),
+			)
+		}
+		fmt.Fprintf(
+			&b,
+			"%s %s seq %d deny %s le %d\n",
+			list.command,
+			list.name,
+			ciscoFirstSeq+len(list.prefixes)*ciscoSeqStep,
+			list.all.String(),
+			list.all.Addr().BitLen(),
+		)
+	}
+
+	return write(w, b.String())
+}
+
+// FRR renders a summary as FRRouting ip prefix-list and ipv6 prefix-list commands, which follow Cisco IOS's syntax,
+// for vtysh or frr.conf.
+type FRR struct {
+	// IPv4List and IPv6List are the names of the prefix lists for each address family.
+	IPv4List string
+	IPv6List string
+	// IPv4Bounds and IPv6Bounds widen the entries for each address family.
+	IPv4Bounds Bounds
+	IPv6Bounds Bounds
+}
+
+// NewFRR returns an FRR rendering into prefix lists named after name, such as name_v4 and name_v6.
+func NewFRR(name string) FRR {
+	return FRR(NewCisco(name))
+}
+
+// Write renders the summary rs to w.
+func (f FRR) Write(w io.Writer, rs *routesum.RouteSum) error {
+	return Cisco(f).Write(w, rs)
+}
+
+// listName matches names router prefix lists accept without quoting.
+func listName() *regexp.Regexp {
+	return regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+}
@@ -0,0 +1,72 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCisco(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "198.51.100.1", "2001:db8::/32")
+
+	var b strings.Builder
+	require.NoError(t, NewCisco("bogons").Write(&b, rs))
+	assert.Equal(t, `no ip prefix-list bogons_v4
+ip prefix-list bogons_v4 seq 5 permit 192.0.2.0/24
+ip prefix-list bogons_v4 seq 10 permit 198.51.100.1/32
+ip prefix-list bogons_v4 seq 15 deny 0.0.0.0/0 le 32
+no ipv6 prefix-list bogons_v6
+ipv6 prefix-list bogons_v6 seq 5 permit 2001:db8::/32
+ipv6 prefix-list bogons_v6 seq 10 deny ::/0 le 128
+`, b.String(), "the lists are replaced")
+
+	f := NewCisco("bogons")
+	f.IPv4Bounds = Bounds{GE: 0, LE: 28}
+	f.IPv6Bounds = Bounds{GE: 48, LE: 0}
+	b.Reset()
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `no ip prefix-list bogons_v4
+ip prefix-list bogons_v4 seq 5 permit 192.0.2.0/24 le 28
+ip prefix-list bogons_v4 seq 10 permit 198.51.100.1/32
+ip prefix-list bogons_v4 seq 15 deny 0.0.0.0/0 le 32
+no ipv6 prefix-list bogons_v6
+ipv6 prefix-list bogons_v6 seq 5 permit 2001:db8::/32 ge 48 le 128
+ipv6 prefix-list bogons_v6 seq 10 deny ::/0 le 128
+`, b.String(), "bounds apply only to shorter networks")
+
+	b.Reset()
+	require.NoError(t, NewCisco("empty").Write(&b, newRouteSum(t)))
+	assert.Equal(t, `no ip prefix-list empty_v4
+ip prefix-list empty_v4 seq 5 deny 0.0.0.0/0 le 32
+no ipv6 prefix-list empty_v6
+ipv6 prefix-list empty_v6 seq 5 deny ::/0 le 128
+`, b.String(), "empty lists still exist, denying everything")
+
+	err := NewCisco("bad name").Write(&b, rs)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	f = NewCisco("bogons")
+	f.IPv4Bounds = Bounds{GE: 28, LE: 24}
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidBounds, "ge must not exceed le")
+	f.IPv4Bounds = Bounds{GE: 0, LE: 33}
+	assert.ErrorIs(t, f.Write(&b, rs), ErrInvalidBounds, "le must fit the address family")
+}
+
+func TestFRR(t *testing.T) {
+	rs := newRouteSum(t, "192.0.2.0/24", "2001:db8::/32")
+
+	f := NewFRR("bogons")
+	f.IPv4Bounds = Bounds{GE: 25, LE: 26}
+
+	var b strings.Builder
+	require.NoError(t, f.Write(&b, rs))
+	assert.Equal(t, `no ip prefix-list bogons_v4
+ip prefix-list bogons_v4 seq 5 permit 192.0.2.0/24 ge 25 le 26
+ip prefix-list bogons_v4 seq 10 deny 0.0.0.0/0 le 32
+no ipv6 prefix-list bogons_v6
+ipv6 prefix-list bogons_v6 seq 5 permit 2001:db8::/32
+ipv6 prefix-list bogons_v6 seq 10 deny ::/0 le 128
+`, b.String())
+}
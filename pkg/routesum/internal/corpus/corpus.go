@@ -0,0 +1,139 @@
+// Package corpus generates deterministic synthetic lists of networks and IPs for benchmarking summarization.
+package corpus
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+)
+
+// Family is an address family to generate networks in.
+type Family struct {
+	Name   string
+	BitLen int
+}
+
+// Families returns IPv4 and IPv6, in that order.
+func Families() []Family {
+	return []Family{
+		{Name: "IPv4", BitLen: 32},
+		{Name: "IPv6", BitLen: 128},
+	}
+}
+
+// Workload is a kind of input to summarize. Generate returns num networks of the given family, the same ones each
+// time it's called with the same arguments. IPs are returned as single-address networks.
+type Workload struct {
+	Name     string
+	Generate func(family Family, num int) []netip.Prefix
+}
+
+// Workloads returns each kind of input benchmarks should cover:
+//
+//   - InsertHeavy: random networks at least half the family's bit length long, so most inserts add a route and few
+//     merge.
+//   - Summarized: networks that are already in their shortest form, in ascending order, so no insert merges anything.
+//   - Random: networks of entirely random length, so the trie is often covered and collapsed by a short network.
+//   - AlternatingSiblings: every other IP of a block, followed by the IPs between them, so the trie grows to its
+//     largest before each of the second half of inserts merges a pair, and the block collapses into one network.
+func Workloads() []Workload {
+	return []Workload{
+		{Name: "InsertHeavy", Generate: InsertHeavy},
+		{Name: "Summarized", Generate: Summarized},
+		{Name: "Random", Generate: Random},
+		{Name: "AlternatingSiblings", Generate: AlternatingSiblings},
+	}
+}
+
+// InsertHeavy returns random networks at least half the family's bit length long.
+func InsertHeavy(family Family, num int) []netip.Prefix {
+	rng := newRand()
+	prefixes := make([]netip.Prefix, 0, num)
+	for range num {
+		prefixLen := family.BitLen/2 + rng.IntN(family.BitLen/2+1)
+		prefixes = append(prefixes, netip.PrefixFrom(randomAddr(rng, family), prefixLen).Masked())
+	}
+
+	return prefixes
+}
+
+// Summarized returns distinct networks of three quarters the family's bit length, none of which are siblings, in
+// ascending order.
+func Summarized(family Family, num int) []netip.Prefix {
+	rng := newRand()
+	prefixLen := family.BitLen * 3 / 4
+	seen := make(map[netip.Prefix]bool, num)
+	prefixes := make([]netip.Prefix, 0, num)
+	for len(prefixes) < num {
+		// Clearing the network's last bit makes every network the lower of a pair of siblings, so none can merge.
+		p := netip.PrefixFrom(randomAddr(rng, family), prefixLen-1).Masked()
+		p = netip.PrefixFrom(p.Addr(), prefixLen)
+		if seen[p] {
+			continue
+		}
+
+		seen[p] = true
+		prefixes = append(prefixes, p)
+	}
+
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		return a.Addr().Compare(b.Addr())
+	})
+
+	return prefixes
+}
+
+// Random returns random networks of random length.
+func Random(family Family, num int) []netip.Prefix {
+	rng := newRand()
+	prefixes := make([]netip.Prefix, 0, num)
+	for range num {
+		prefixLen := 1 + rng.IntN(family.BitLen)
+		prefixes = append(prefixes, netip.PrefixFrom(randomAddr(rng, family), prefixLen).Masked())
+	}
+
+	return prefixes
+}
+
+// AlternatingSiblings returns the even IPs of a randomly placed block of num IPs, followed by its odd IPs.
+func AlternatingSiblings(family Family, num int) []netip.Prefix {
+	rng := newRand()
+
+	// Start at the beginning of a randomly placed aligned block big enough for every IP, so that each odd IP is the
+	// sibling of the even IP before it, and the block doesn't run off the end of the address space.
+	hostBits := bits.Len(uint(num)) //nolint: gosec
+	start := netip.PrefixFrom(randomAddr(rng, family), family.BitLen-hostBits).Masked().Addr()
+
+	evens := make([]netip.Prefix, 0, (num+1)/2)
+	odds := make([]netip.Prefix, 0, num/2)
+	addr := start
+	for i := range num {
+		p := netip.PrefixFrom(addr, family.BitLen)
+		if i%2 == 0 {
+			evens = append(evens, p)
+		} else {
+			odds = append(odds, p)
+		}
+		addr = addr.Next()
+	}
+
+	return append(evens, odds...)
+}
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewPCG(1, 2)) //nolint: gosec
+}
+
+func randomAddr(rng *rand.Rand, family Family) netip.Addr {
+	var addrBytes [16]byte
+	for i := range addrBytes {
+		addrBytes[i] = byte(rng.UintN(256))
+	}
+
+	if family.BitLen == 32 {
+		return netip.AddrFrom4([4]byte(addrBytes[:4]))
+	}
+
+	return netip.AddrFrom16(addrBytes)
+}
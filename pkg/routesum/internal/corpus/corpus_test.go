@@ -0,0 +1,62 @@
+package corpus
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkloads(t *testing.T) {
+	for _, workload := range Workloads() {
+		for _, family := range Families() {
+			t.Run(workload.Name+"/"+family.Name, func(t *testing.T) {
+				prefixes := workload.Generate(family, 1000)
+				assert.Len(t, prefixes, 1000, "got requested number of networks")
+				assert.Equal(t, prefixes, workload.Generate(family, 1000), "networks are deterministic")
+
+				for _, p := range prefixes {
+					assert.Equal(t, family.BitLen, p.Addr().BitLen(), "%s is in the requested family", p)
+					assert.Equal(t, p.Masked(), p, "%s has no host bits set", p)
+				}
+			})
+		}
+	}
+}
+
+func TestSummarized(t *testing.T) {
+	prefixes := Summarized(Families()[0], 1000)
+	seen := map[netip.Prefix]bool{}
+	for i, p := range prefixes {
+		assert.False(t, seen[p], "%s is distinct", p)
+		seen[p] = true
+
+		if i > 0 {
+			assert.Equal(t, -1, prefixes[i-1].Addr().Compare(p.Addr()), "%s is in ascending order", p)
+		}
+	}
+
+	for _, p := range prefixes {
+		addr := binary.BigEndian.Uint32(p.Addr().AsSlice())
+		var siblingAddr [4]byte
+		binary.BigEndian.PutUint32(siblingAddr[:], addr^1<<(32-p.Bits()))
+		assert.False(t, seen[netip.PrefixFrom(netip.AddrFrom4(siblingAddr), p.Bits())], "%s has no sibling", p)
+	}
+}
+
+func TestAlternatingSiblings(t *testing.T) {
+	prefixes := AlternatingSiblings(Families()[1], 6)
+	start := prefixes[0].Addr()
+	assert.Equal(t, byte(0), start.As16()[15]&1, "block starts at an even IP")
+
+	offsets := make([]int, 0, len(prefixes))
+	for _, p := range prefixes {
+		offset := 0
+		for addr := start; addr != p.Addr(); addr = addr.Next() {
+			offset++
+		}
+		offsets = append(offsets, offset)
+	}
+	assert.Equal(t, []int{0, 2, 4, 1, 3, 5}, offsets, "even IPs come before odd IPs")
+}
@@ -0,0 +1,172 @@
+package routesum
+
+import (
+	"fmt"
+	"iter"
+	"net/netip"
+	"strings"
+)
+
+// IPRange is a contiguous range of IPs of a single address family, from From to To, inclusive.
+type IPRange struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// String returns the range as its first and last IPs separated by a hyphen, such as 192.0.2.10-192.0.2.77. The IPs
+// are the same for a single-IP range.
+func (r IPRange) String() string {
+	return r.From.String() + "-" + r.To.String()
+}
+
+// EachRange returns an iterator that returns each range of IPs stored, in ascending order. Stored networks that
+// adjoin one another are coalesced into a single range, so each range returned is as long as it can be.
+func (rs *RouteSum) EachRange() iter.Seq[IPRange] {
+	return eachRange(rs.EachPrefix(), false)
+}
+
+// EachRangeDescending returns an iterator that returns each range of IPs stored, in exactly the reverse of
+// EachRange's order.
+func (rs *RouteSum) EachRangeDescending() iter.Seq[IPRange] {
+	return eachRange(rs.EachPrefixDescending(), true)
+}
+
+// eachRange coalesces a sequence of ordered, non-overlapping networks into ranges. When descending, each network
+// comes before the networks below it.
+func eachRange(prefixes iter.Seq[netip.Prefix], descending bool) iter.Seq[IPRange] {
+	return func(yield func(IPRange) bool) {
+		var cur IPRange
+		for p := range prefixes {
+			next := IPRange{From: p.Masked().Addr(), To: lastAddr(p)}
+			switch {
+			case !cur.From.IsValid():
+				cur = next
+				continue
+			case !descending && cur.To.Next() == next.From:
+				cur.To = next.To
+				continue
+			case descending && next.To.Next() == cur.From:
+				cur.From = next.From
+				continue
+			}
+
+			if !yield(cur) {
+				return
+			}
+			cur = next
+		}
+
+		if cur.From.IsValid() {
+			yield(cur)
+		}
+	}
+}
+
+// InsertRange adds every IP from one IP to another, inclusive, to the summary. Both IPs must be of the same address
+// family, and from must not come after to.
+func (rs *RouteSum) InsertRange(from, to netip.Addr) error {
+	return rs.insertRange(from, to, IPRange{From: from, To: to}.String(), Source{File: "", Line: 0})
+}
+
+// insertRange inserts a range given as value.
+func (rs *RouteSum) insertRange(from, to netip.Addr, value string, source Source) error {
+	if err := validateRange(from, to, value); err != nil {
+		return withSource(err, source)
+	}
+
+	for _, p := range rangePrefixes(from, to) {
+		if err := rs.insertPrefix(p, source); err != nil {
+			return withSource(err, source)
+		}
+	}
+
+	// A range counts as one input, however many networks it takes to cover it.
+	rs.inputs.Add(1)
+
+	return nil
+}
+
+// isRange reports whether a string looks like a range of IPs, such as 192.0.2.10-192.0.2.77, rather than a network or
+// IP. Neither IPv4 nor IPv6 addresses contain a hyphen.
+func isRange(s string) bool {
+	return strings.Contains(s, "-")
+}
+
+// parseRange parses a range of IPs given as its first and last IPs separated by a hyphen, optionally surrounded by
+// spaces.
+func parseRange(s string) (netip.Addr, netip.Addr, error) {
+	fromStr, toStr, _ := strings.Cut(s, "-")
+
+	from, err := parseRangeAddr(fromStr)
+	if err != nil {
+		err = fmt.Errorf("parse range start: %w", err)
+		return netip.Addr{}, netip.Addr{}, wrapInvalidInputErr(s, ReasonInvalidRange, err)
+	}
+
+	to, err := parseRangeAddr(toStr)
+	if err != nil {
+		err = fmt.Errorf("parse range end: %w", err)
+		return netip.Addr{}, netip.Addr{}, wrapInvalidInputErr(s, ReasonInvalidRange, err)
+	}
+
+	return from, to, nil
+}
+
+func parseRangeAddr(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("parse IP: %w", err)
+	}
+	if ip.Zone() != "" {
+		return netip.Addr{}, newInvalidInputErr(s, ReasonInvalidIP, "IPs with zones are not supported")
+	}
+
+	return ip, nil
+}
+
+// validateRange checks that a range, given as value, is of valid IPs of the same address family, in order.
+func validateRange(from, to netip.Addr, value string) error {
+	switch {
+	case !from.IsValid() || !to.IsValid():
+		return newInvalidInputErr(value, ReasonInvalidRange, "the range's IPs must be valid")
+	case from.Is4() != to.Is4():
+		return newInvalidInputErr(value, ReasonInvalidRange, "the range's IPs must be of the same address family")
+	case to.Less(from):
+		return newInvalidInputErr(value, ReasonInvalidRange, "the range's end must not come before its start")
+	}
+
+	return nil
+}
+
+// rangePrefixes returns the fewest networks that together cover exactly the IPs from one IP to another, inclusive, in
+// ascending order. The range must be valid.
+func rangePrefixes(from, to netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for cur := from; cur.IsValid() && !to.Less(cur); {
+		// The largest network starting at cur that doesn't extend past to is the next one needed.
+		for bits := range cur.BitLen() + 1 {
+			p := netip.PrefixFrom(cur, bits)
+			if p.Masked().Addr() != cur || to.Less(lastAddr(p)) {
+				continue
+			}
+
+			prefixes = append(prefixes, p)
+			cur = lastAddr(p).Next()
+			break
+		}
+	}
+
+	return prefixes
+}
+
+// lastAddr returns the last IP in a network.
+func lastAddr(p netip.Prefix) netip.Addr {
+	addrBytes := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(addrBytes)*8; i++ {
+		addrBytes[i/8] |= 0x80 >> (i % 8)
+	}
+
+	addr, _ := netip.AddrFromSlice(addrBytes)
+	return addr
+}
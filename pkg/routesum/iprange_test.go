@@ -0,0 +1,230 @@
+package routesum
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangePrefixes(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name     string
+		from, to string
+		expected []string
+	}{
+		{
+			name: "single IP",
+			from: "192.0.2.1",
+			to:   "192.0.2.1",
+			expected: []string{
+				"192.0.2.1/32",
+			},
+		},
+		{
+			name: "aligned network",
+			from: "192.0.2.0",
+			to:   "192.0.2.255",
+			expected: []string{
+				"192.0.2.0/24",
+			},
+		},
+		{
+			name: "unaligned range",
+			from: "192.0.2.10",
+			to:   "192.0.2.77",
+			expected: []string{
+				"192.0.2.10/31",
+				"192.0.2.12/30",
+				"192.0.2.16/28",
+				"192.0.2.32/27",
+				"192.0.2.64/29",
+				"192.0.2.72/30",
+				"192.0.2.76/31",
+			},
+		},
+		{
+			name: "whole IPv4 address space",
+			from: "0.0.0.0",
+			to:   "255.255.255.255",
+			expected: []string{
+				"0.0.0.0/0",
+			},
+		},
+		{
+			name: "end of IPv4 address space",
+			from: "255.255.255.254",
+			to:   "255.255.255.255",
+			expected: []string{
+				"255.255.255.254/31",
+			},
+		},
+		{
+			name: "IPv6",
+			from: "2001:db8::1",
+			to:   "2001:db8::1:0",
+			expected: []string{
+				"2001:db8::1/128",
+				"2001:db8::2/127",
+				"2001:db8::4/126",
+				"2001:db8::8/125",
+				"2001:db8::10/124",
+				"2001:db8::20/123",
+				"2001:db8::40/122",
+				"2001:db8::80/121",
+				"2001:db8::100/120",
+				"2001:db8::200/119",
+				"2001:db8::400/118",
+				"2001:db8::800/117",
+				"2001:db8::1000/116",
+				"2001:db8::2000/115",
+				"2001:db8::4000/114",
+				"2001:db8::8000/113",
+				"2001:db8::1:0/128",
+			},
+		},
+		{
+			name: "whole IPv6 address space",
+			from: "::",
+			to:   "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			expected: []string{
+				"::/0",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefixes := rangePrefixes(netip.MustParseAddr(test.from), netip.MustParseAddr(test.to))
+
+			got := make([]string, 0, len(prefixes))
+			for _, p := range prefixes {
+				got = append(got, p.String())
+			}
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestInsertRange(t *testing.T) {
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertRange(netip.MustParseAddr("192.0.2.0"), netip.MustParseAddr("192.0.2.127")))
+	require.NoError(t, rs.InsertRange(netip.MustParseAddr("192.0.2.128"), netip.MustParseAddr("192.0.2.255")))
+	require.NoError(t, rs.InsertRange(netip.MustParseAddr("2001:db8::"), netip.MustParseAddr("2001:db8::2")))
+
+	assert.Equal(t, []string{"192.0.2.0/24", "2001:db8::/127", "2001:db8::2"}, rs.SummaryStrings())
+	assert.Equal(t, int64(3), rs.Stats().Inputs, "a range counts as one input")
+
+	invalidRanges := []struct {
+		name     string
+		from, to netip.Addr
+		expected string
+	}{
+		{
+			name:     "reversed",
+			from:     netip.MustParseAddr("192.0.2.2"),
+			to:       netip.MustParseAddr("192.0.2.1"),
+			expected: "the range's end must not come before its start",
+		},
+		{
+			name:     "mixed families",
+			from:     netip.MustParseAddr("192.0.2.1"),
+			to:       netip.MustParseAddr("2001:db8::1"),
+			expected: "the range's IPs must be of the same address family",
+		},
+		{
+			name:     "invalid IP",
+			from:     netip.Addr{},
+			to:       netip.MustParseAddr("192.0.2.1"),
+			expected: "the range's IPs must be valid",
+		},
+	}
+	for _, test := range invalidRanges {
+		t.Run(test.name, func(t *testing.T) {
+			rs := NewRouteSum()
+			err := rs.InsertRange(test.from, test.to)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+			assert.Equal(t, []string(nil), rs.SummaryStrings(), "nothing was added")
+		})
+	}
+}
+
+func TestInsertRangeFromString(t *testing.T) {
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertFromString("192.0.2.10-192.0.2.11"))
+	require.NoError(t, rs.InsertFromString("192.0.2.12 - 192.0.2.15"))
+	assert.Equal(t, []string{"192.0.2.10/31", "192.0.2.12/30"}, rs.SummaryStrings())
+
+	invalidRanges := map[string]string{
+		"192.0.2.1-":                    "parse range end",
+		"-192.0.2.1":                    "parse range start",
+		"192.0.2.1-192.0.2":             "parse range end",
+		"192.0.2.1-192.0.2.2-192.0.2.3": "parse range end",
+		"fe80::1%eth0-fe80::2":          "parse range start",
+		"192.0.2.2-192.0.2.1":           "the range's end must not come before its start",
+		"192.0.2.1-2001:db8::1":         "the range's IPs must be of the same address family",
+	}
+	for s, expected := range invalidRanges {
+		t.Run(s, func(t *testing.T) {
+			rs := NewRouteSum()
+			err := rs.InsertFromString(s)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), expected)
+			}
+			assert.Equal(t, []string(nil), rs.SummaryStrings(), "nothing was added")
+		})
+	}
+}
+
+func TestEachRange(t *testing.T) {
+	rs := NewRouteSum()
+	for _, s := range []string{
+		"0.0.0.0/8",
+		"192.0.2.10/31",
+		"192.0.2.12/30",
+		"192.0.2.17",
+		"192.0.2.18/31",
+		"255.255.255.255",
+		"::/127",
+		"2001:db8::/32",
+	} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	expected := []string{
+		"0.0.0.0-0.255.255.255",
+		"192.0.2.10-192.0.2.15",
+		"192.0.2.17-192.0.2.19",
+		"255.255.255.255-255.255.255.255",
+		"::-::1",
+		"2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff",
+	}
+
+	var got []string
+	for r := range rs.EachRange() {
+		got = append(got, r.String())
+	}
+	assert.Equal(t, expected, got, "ranges are coalesced in ascending order")
+
+	got = nil
+	for r := range rs.EachRangeDescending() {
+		got = append(got, r.String())
+	}
+	slices.Reverse(expected)
+	assert.Equal(t, expected, got, "ranges are coalesced in descending order")
+
+	for range NewRouteSum().EachRange() {
+		assert.Fail(t, "an empty summary has no ranges")
+	}
+
+	// Ranges can be read back in as the same summary.
+	roundTripped := NewRouteSum()
+	for r := range rs.EachRange() {
+		require.NoError(t, roundTripped.InsertFromString(r.String()))
+	}
+	assert.Equal(t, rs.SummaryStrings(), roundTripped.SummaryStrings())
+}
@@ -0,0 +1,84 @@
+package routesum
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// isMasked reports whether a string looks like an IP followed by a netmask or wildcard mask, such as
+// 192.0.2.0 255.255.255.0 or 192.0.2.0 0.0.0.255, rather than a CIDR-formatted network or IP.
+func isMasked(s string) bool {
+	return len(strings.Fields(s)) == 2 && !strings.Contains(s, "/")
+}
+
+// parseMasked parses an IP followed by a netmask or wildcard mask, as found in router configs and ACLs. A mask of
+// ones followed by zeros is a netmask, and one of zeros followed by ones is a wildcard mask. A mask of all zeros or
+// all ones could be either, so it's read as the whole address space following the unspecified address, as in a
+// default route, and as a single IP following any other.
+func parseMasked(s string) (netip.Prefix, error) {
+	fields := strings.Fields(s)
+
+	ip, err := netip.ParseAddr(fields[0])
+	if err != nil {
+		return netip.Prefix{}, wrapInvalidInputErr(s, ReasonInvalidIP, err)
+	}
+	if ip.Zone() != "" {
+		return netip.Prefix{}, newInvalidInputErr(s, ReasonInvalidIP, "IPs with zones are not supported")
+	}
+
+	mask, err := netip.ParseAddr(fields[1])
+	if err != nil {
+		return netip.Prefix{}, wrapInvalidInputErr(s, ReasonInvalidMask, fmt.Errorf("parse mask: %w", err))
+	}
+	if mask.BitLen() != ip.BitLen() || mask.Zone() != "" {
+		return netip.Prefix{}, newInvalidInputErr(
+			s,
+			ReasonInvalidMask,
+			"the mask is not of the same address family as the IP",
+		)
+	}
+
+	maskBytes := mask.AsSlice()
+	netmaskLen, isNetmask := leadingOnesLen(maskBytes)
+	for i := range maskBytes {
+		maskBytes[i] = ^maskBytes[i]
+	}
+	wildcardLen, isWildcard := leadingOnesLen(maskBytes)
+
+	switch {
+	case isNetmask && isWildcard && ip.IsUnspecified():
+		return netip.PrefixFrom(ip, 0), nil
+	case isNetmask && isWildcard:
+		return netip.PrefixFrom(ip, ip.BitLen()), nil
+	case isNetmask:
+		return netip.PrefixFrom(ip, netmaskLen), nil
+	case isWildcard:
+		return netip.PrefixFrom(ip, wildcardLen), nil
+	}
+
+	return netip.Prefix{}, newInvalidInputErr(
+		s,
+		ReasonInvalidMask,
+		fields[1]+" is neither a contiguous netmask nor a contiguous wildcard mask",
+	)
+}
+
+// leadingOnesLen returns the number of leading one bits in mask, and whether every bit after them is zero.
+func leadingOnesLen(mask []byte) (int, bool) {
+	onesLen := 0
+	seenZero := false
+	for i := range len(mask) * 8 {
+		if mask[i/8]>>(7-i%8)&1 == 0 {
+			seenZero = true
+			continue
+		}
+
+		if seenZero {
+			return 0, false
+		}
+		onesLen++
+	}
+
+	return onesLen, true
+}
@@ -0,0 +1,72 @@
+package routesum
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMasked(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.0 255.255.255.0":                    "192.0.2.0/24",
+		"192.0.2.0 0.0.0.255":                        "192.0.2.0/24",
+		"192.0.2.0  255.255.254.0":                   "192.0.2.0/23",
+		"198.51.100.4 0.0.0.3":                       "198.51.100.4/30",
+		"192.0.2.1 255.255.255.255":                  "192.0.2.1/32",
+		"192.0.2.1 0.0.0.0":                          "192.0.2.1/32",
+		"0.0.0.0 0.0.0.0":                            "0.0.0.0/0",
+		"0.0.0.0 255.255.255.255":                    "0.0.0.0/0",
+		"10.0.0.0 255.0.0.0":                         "10.0.0.0/8",
+		"10.0.0.0 0.255.255.255":                     "10.0.0.0/8",
+		"2001:db8:: ffff:ffff::":                     "2001:db8::/32",
+		"2001:db8:: ::ffff:ffff:ffff:ffff:ffff:ffff": "2001:db8::/32",
+	}
+	for s, expected := range tests {
+		t.Run(s, func(t *testing.T) {
+			p, err := parsePrefix(s)
+			require.NoError(t, err)
+			assert.Equal(t, expected, p.String())
+		})
+	}
+}
+
+func TestParseMaskedInvalid(t *testing.T) {
+	invalidMasks := map[string]string{
+		"192.0.2.0 255.0.255.0":   "255.0.255.0 is neither a contiguous netmask nor a contiguous wildcard mask",
+		"192.0.2.0 0.255.0.255":   "0.255.0.255 is neither a contiguous netmask nor a contiguous wildcard mask",
+		"192.0.2.0 255.255.255.1": "255.255.255.1 is neither a contiguous netmask nor a contiguous wildcard mask",
+		"192.0.2.0 ffff:ffff::":   "the mask is not of the same address family as the IP",
+	}
+	for s, reason := range invalidMasks {
+		t.Run(s, func(t *testing.T) {
+			rs := NewRouteSum()
+			err := rs.InsertFromString(s)
+
+			var iIErr *InvalidInputErr
+			if assert.True(t, errors.As(err, &iIErr), "error is an InvalidInputErr") {
+				assert.Equal(t, s, iIErr.InvalidValue)
+				assert.Equal(t, reason, iIErr.Reason)
+			}
+			assert.Equal(t, []string(nil), rs.SummaryStrings(), "nothing was added")
+		})
+	}
+
+	for _, s := range []string{"192.0.2.0 255.255.255", "192.0.2 255.255.255.0", "fe80::%eth0 ffff::"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := parsePrefix(s)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestInsertMaskedFromString(t *testing.T) {
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertFromString("192.0.2.0 255.255.255.128"))
+	require.NoError(t, rs.InsertFromString("192.0.2.128 0.0.0.127"))
+	assert.Equal(t, []string{"192.0.2.0/24"}, rs.SummaryStrings())
+
+	require.NoError(t, rs.RemoveFromString("192.0.2.0 0.0.0.127"))
+	assert.Equal(t, []string{"192.0.2.128/25"}, rs.SummaryStrings())
+}
@@ -0,0 +1,133 @@
+package routesum
+
+import (
+	"iter"
+	"net/netip"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
+)
+
+// RouteMap is like RouteSum, but associates a value with each network and IP. Neighboring networks are summarized
+// only when their values allow, as decided by the merge function the RouteMap was created with.
+type RouteMap[V any] struct {
+	ipv4, ipv6 *rstrie.Map[V]
+}
+
+// NewRouteMap returns an initialized RouteMap object. When two neighboring networks could be summarized, merge is
+// called with the value of the lower network and then the upper one. It returns the value of the summarized network,
+// and whether to summarize them at all. MergeEqual is a merge function that summarizes networks with equal values.
+func NewRouteMap[V any](merge func(lower, upper V) (V, bool)) *RouteMap[V] {
+	return &RouteMap[V]{
+		ipv4: rstrie.NewMap(merge),
+		ipv6: rstrie.NewMap(merge),
+	}
+}
+
+// MergeEqual is a merge function for NewRouteMap that summarizes networks only when their values are equal.
+func MergeEqual[V comparable](lower, upper V) (V, bool) {
+	return rstrie.MergeEqual(lower, upper)
+}
+
+// InsertFromString associates a value with either a string-formatted network or IP. Any value previously associated
+// with the addresses it covers is replaced.
+func (rm *RouteMap[V]) InsertFromString(s string, value V) error {
+	ipPrefix, err := parsePrefix(s)
+	if err != nil {
+		return err
+	}
+
+	return rm.InsertPrefix(ipPrefix, value)
+}
+
+// InsertPrefix associates a value with a network, as InsertFromString does.
+func (rm *RouteMap[V]) InsertPrefix(ipPrefix netip.Prefix, value V) error {
+	m, ipBits, err := rm.mapAndBitsForIPPrefix(ipPrefix)
+	if err != nil {
+		return err
+	}
+
+	m.InsertRoute(ipBits, value)
+
+	return nil
+}
+
+// InsertAddr associates a value with an IP, as InsertFromString does.
+func (rm *RouteMap[V]) InsertAddr(ip netip.Addr, value V) error {
+	if !ip.IsValid() {
+		return newInvalidInputErr(ip.String(), ReasonInvalidIP, "not a valid IP")
+	}
+
+	return rm.InsertPrefix(netip.PrefixFrom(ip, ip.BitLen()), value)
+}
+
+// Lookup returns the network covering an IP, and its value, if there is one.
+func (rm *RouteMap[V]) Lookup(ip netip.Addr) (netip.Prefix, V, bool) {
+	var zero V
+	if !ip.IsValid() {
+		return netip.Prefix{}, zero, false
+	}
+
+	m, ipBits, err := rm.mapAndBitsForIPPrefix(netip.PrefixFrom(ip, ip.BitLen()))
+	if err != nil {
+		return netip.Prefix{}, zero, false
+	}
+
+	coveringBits, value, ok := m.Lookup(ipBits)
+	if !ok {
+		return netip.Prefix{}, zero, false
+	}
+
+	if m == rm.ipv4 {
+		return ipv4PrefixFromBits(coveringBits), value, true
+	}
+
+	return ipv6PrefixFromBits(coveringBits), value, true
+}
+
+// Each returns an iterator that returns each IP or prefix stored, with its value. The order is the same as
+// RouteSum.Each's.
+func (rm *RouteMap[V]) Each() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		for prefix, value := range rm.EachPrefix() {
+			if !yield(PrefixString(prefix), value) {
+				return
+			}
+		}
+	}
+}
+
+// EachPrefix returns an iterator that returns each prefix stored, with its value. IPs are returned as single-address
+// prefixes. The order is the same as RouteSum.EachPrefix's.
+func (rm *RouteMap[V]) EachPrefix() iter.Seq2[netip.Prefix, V] {
+	return func(yield func(netip.Prefix, V) bool) {
+		for bits, value := range rm.ipv4.Each() {
+			if !yield(ipv4PrefixFromBits(bits), value) {
+				return
+			}
+		}
+
+		for bits, value := range rm.ipv6.Each() {
+			if !yield(ipv6PrefixFromBits(bits), value) {
+				return
+			}
+		}
+	}
+}
+
+func (rm *RouteMap[V]) mapAndBitsForIPPrefix(ipPrefix netip.Prefix) (*rstrie.Map[V], bitslice.BitSlice, error) {
+	if !ipPrefix.IsValid() {
+		return nil, bitslice.BitSlice{}, newInvalidInputErr(ipPrefix.String(), ReasonInvalidNetwork, "not valid CIDR")
+	}
+
+	ipBits, err := ipBitsForIPPrefix(ipPrefix)
+	if err != nil {
+		return nil, bitslice.BitSlice{}, err
+	}
+
+	if ipPrefix.Addr().Is4() {
+		return rm.ipv4, ipBits, nil
+	}
+
+	return rm.ipv6, ipBits, nil
+}
@@ -0,0 +1,124 @@
+package routesum
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type prefixValue struct {
+	prefix string
+	value  string
+}
+
+func TestRouteMap(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name     string
+		input    []prefixValue
+		expected []prefixValue
+	}{
+		{
+			name: "networks with equal values are summarized",
+			input: []prefixValue{
+				{"192.0.2.0/25", "feed-a"},
+				{"192.0.2.128/25", "feed-a"},
+				{"2001:db8::", "feed-a"},
+				{"2001:db8::1", "feed-a"},
+			},
+			expected: []prefixValue{
+				{"192.0.2.0/24", "feed-a"},
+				{"2001:db8::/127", "feed-a"},
+			},
+		},
+		{
+			name: "networks with different values are not",
+			input: []prefixValue{
+				{"192.0.2.0/25", "feed-a"},
+				{"192.0.2.128/25", "feed-b"},
+			},
+			expected: []prefixValue{
+				{"192.0.2.0/25", "feed-a"},
+				{"192.0.2.128/25", "feed-b"},
+			},
+		},
+		{
+			name: "later values replace earlier ones",
+			input: []prefixValue{
+				{"192.0.2.0/30", "feed-a"},
+				{"192.0.2.1", "feed-b"},
+			},
+			expected: []prefixValue{
+				{"192.0.2.0", "feed-a"},
+				{"192.0.2.1", "feed-b"},
+				{"192.0.2.2/31", "feed-a"},
+			},
+		},
+		{
+			name: "address families are kept apart",
+			input: []prefixValue{
+				{"192.0.2.0", "feed-a"},
+				{"::ffff:192.0.2.0", "feed-a"},
+			},
+			expected: []prefixValue{
+				{"192.0.2.0", "feed-a"},
+				{"::ffff:192.0.2.0", "feed-a"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rm := NewRouteMap(MergeEqual[string])
+			for _, pv := range test.input {
+				require.NoError(t, rm.InsertFromString(pv.prefix, pv.value))
+			}
+
+			var got []prefixValue
+			for s, value := range rm.Each() {
+				got = append(got, prefixValue{s, value})
+			}
+			assert.Equal(t, test.expected, got, "got expected summary")
+		})
+	}
+}
+
+func TestRouteMapMergeFunc(t *testing.T) {
+	rm := NewRouteMap(func(lower, upper []string) ([]string, bool) {
+		merged := slices.Concat(lower, upper)
+		slices.Sort(merged)
+		return slices.Compact(merged), true
+	})
+	require.NoError(t, rm.InsertPrefix(netip.MustParsePrefix("198.51.100.0/25"), []string{"feed-a"}))
+	require.NoError(t, rm.InsertPrefix(netip.MustParsePrefix("198.51.100.128/25"), []string{"feed-b"}))
+	require.NoError(t, rm.InsertAddr(netip.MustParseAddr("2001:db8::"), []string{"feed-c"}))
+
+	var got []string
+	for prefix, value := range rm.EachPrefix() {
+		got = append(got, prefix.String()+" "+strings.Join(value, ","))
+	}
+	assert.Equal(
+		t,
+		[]string{"198.51.100.0/24 feed-a,feed-b", "2001:db8::/128 feed-c"},
+		got,
+		"merge function combines values",
+	)
+
+	prefix, value, found := rm.Lookup(netip.MustParseAddr("198.51.100.7"))
+	assert.True(t, found, "covered IP is found")
+	assert.Equal(t, netip.MustParsePrefix("198.51.100.0/24"), prefix, "got covering network")
+	assert.Equal(t, []string{"feed-a", "feed-b"}, value, "got covering network's value")
+
+	_, _, found = rm.Lookup(netip.MustParseAddr("2001:db8::1"))
+	assert.False(t, found, "uncovered IP is not found")
+
+	_, _, found = rm.Lookup(netip.Addr{})
+	assert.False(t, found, "invalid IP is not found")
+
+	assert.Error(t, rm.InsertFromString("not an IP", nil), "invalid input is rejected")
+	assert.Error(t, rm.InsertPrefix(netip.Prefix{}, nil), "invalid network is rejected")
+	assert.Error(t, rm.InsertAddr(netip.Addr{}, nil), "invalid IP is rejected")
+}
@@ -4,99 +4,399 @@ package routesum
 import (
 	"fmt"
 	"iter"
+	"math/big"
 	"net/netip"
 	"slices"
 	"strings"
+	"sync/atomic"
 
 	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
 	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
-	"github.com/pkg/errors"
 )
 
 // RouteSum has methods supporting route summarization of networks and hosts
 type RouteSum struct {
-	ipv4, ipv6 *rstrie.RSTrie
+	ipv4, ipv6     *rstrie.RSTrie
+	provenance     *provenance
+	inputs         atomic.Int64
+	strict         bool
+	warnHostBits   func(Input)
+	unmapping      []Embedding
+	embeddedOutput Embedding
+}
+
+// Option configures a RouteSum made by NewRouteSum.
+type Option func(*RouteSum)
+
+// WithProvenance makes the RouteSum record each network and IP inserted, so that Explain can report what each
+// summarized network was made from.
+func WithProvenance() Option {
+	return func(rs *RouteSum) {
+		rs.provenance = newProvenance()
+	}
+}
+
+// WithStrict makes the RouteSum reject networks with host bits set, such as 192.0.2.5/24, which are usually typos,
+// rather than inserting the network they're in.
+func WithStrict() Option {
+	return func(rs *RouteSum) {
+		rs.strict = true
+	}
+}
+
+// WithHostBitsWarning makes the RouteSum call warn with each network inserted with host bits set, such as
+// 192.0.2.5/24, before inserting the network it's in. The Input's Prefix is as it was given. WithStrict takes
+// precedence.
+func WithHostBitsWarning(warn func(Input)) Option {
+	return func(rs *RouteSum) {
+		rs.warnHostBits = warn
+	}
 }
 
 // NewRouteSum returns an initialized RouteSum object
-func NewRouteSum() *RouteSum {
-	rs := new(RouteSum)
-	rs.ipv4 = rstrie.NewRSTrie()
-	rs.ipv6 = rstrie.NewRSTrie()
+func NewRouteSum(opts ...Option) *RouteSum {
+	rs := newRouteSumFromTries(rstrie.NewRSTrie(), rstrie.NewRSTrie())
+	for _, opt := range opts {
+		opt(rs)
+	}
 
 	return rs
 }
 
-// InsertFromString adds either a string-formatted network or IP to the summary
+func newRouteSumFromTries(ipv4, ipv6 *rstrie.RSTrie) *RouteSum {
+	return &RouteSum{
+		ipv4:           ipv4,
+		ipv6:           ipv6,
+		provenance:     nil,
+		inputs:         atomic.Int64{},
+		strict:         false,
+		warnHostBits:   nil,
+		unmapping:      nil,
+		embeddedOutput: 0,
+	}
+}
+
+// withTries returns a new RouteSum of the given tries, unmapping and outputting networks as rs does.
+func (rs *RouteSum) withTries(ipv4, ipv6 *rstrie.RSTrie) *RouteSum {
+	derived := newRouteSumFromTries(ipv4, ipv6)
+	derived.unmapping = rs.unmapping
+	derived.embeddedOutput = rs.embeddedOutput
+
+	return derived
+}
+
+// InsertFromString adds either a string-formatted network, IP or range of IPs to the summary. A network may be
+// CIDR-formatted, or given as an IP followed by a netmask or wildcard mask, such as 192.0.2.0 255.255.255.0 or
+// 192.0.2.0 0.0.0.255. A range is given as its first and last IPs separated by a hyphen, such as
+// 192.0.2.10-192.0.2.77 or 192.0.2.10 - 192.0.2.77.
 func (rs *RouteSum) InsertFromString(s string) error {
-	var ip netip.Addr
-	var ipBits bitslice.BitSlice
-	var err error
+	return rs.InsertFromStringWithSource(s, Source{File: "", Line: 0})
+}
 
-	if strings.Contains(s, "/") {
-		ipPrefix, err := netip.ParsePrefix(s)
+// InsertFromStringWithSource adds either a string-formatted network, IP or range of IPs to the summary, as
+// InsertFromString does. If the RouteSum was made WithProvenance, source is recorded as where it came from.
+func (rs *RouteSum) InsertFromStringWithSource(s string, source Source) error {
+	if isRange(s) {
+		from, to, err := parseRange(s)
 		if err != nil {
-			return fmt.Errorf("parse network: %w", err)
-		}
-		if !ipPrefix.IsValid() {
-			return errors.Errorf("%s is not valid CIDR", s)
+			return withSource(err, source)
 		}
 
-		ip = ipPrefix.Addr()
-		ipBits, err = ipBitsForIPPrefix(ipPrefix)
-		if err != nil {
-			return err
-		}
-	} else {
-		ip, err = netip.ParseAddr(s)
-		if err != nil {
-			return fmt.Errorf("parse IP: %w", err)
+		return rs.insertRange(from, to, s, source)
+	}
+
+	ipPrefix, err := parsePrefix(s)
+	if err != nil {
+		return withSource(err, source)
+	}
+
+	return rs.insertInput(ipPrefix, s, source)
+}
+
+// InsertPrefix adds a network to the summary
+func (rs *RouteSum) InsertPrefix(ipPrefix netip.Prefix) error {
+	return rs.InsertPrefixWithSource(ipPrefix, Source{File: "", Line: 0})
+}
+
+// InsertPrefixWithSource adds a network to the summary, as InsertPrefix does. If the RouteSum was made
+// WithProvenance, source is recorded as where it came from.
+func (rs *RouteSum) InsertPrefixWithSource(ipPrefix netip.Prefix, source Source) error {
+	return rs.insertInput(ipPrefix, "", source)
+}
+
+// insertInput inserts a network, checking it for host bits first. value is the network as it was given, if it was
+// given as a string; otherwise it's formatted only when an error needs it.
+func (rs *RouteSum) insertInput(ipPrefix netip.Prefix, value string, source Source) error {
+	if ipPrefix.IsValid() && ipPrefix != ipPrefix.Masked() {
+		if rs.strict {
+			if value == "" {
+				value = ipPrefix.String()
+			}
+			reason := "host bits are set; the network is " + ipPrefix.Masked().String()
+			return withSource(newInvalidInputErr(value, ReasonHostBitsSet, reason), source)
 		}
-		if !ip.IsValid() {
-			return errors.Errorf("%s is not a valid IP", s)
+		if rs.warnHostBits != nil {
+			rs.warnHostBits(Input{Source: source, Prefix: ipPrefix})
 		}
+	}
 
-		ipBits, err = ipBitsForIP(ip)
-		if err != nil {
-			return err
-		}
+	if err := rs.insertPrefix(ipPrefix, source); err != nil {
+		return withSource(err, source)
 	}
+	rs.inputs.Add(1)
 
-	if ip.Is4() {
-		rs.ipv4.InsertRoute(ipBits)
-	} else {
-		rs.ipv6.InsertRoute(ipBits)
+	return nil
+}
+
+func (rs *RouteSum) insertPrefix(ipPrefix netip.Prefix, source Source) error {
+	trie, ipBits, err := rs.trieAndBitsForIPPrefix(ipPrefix)
+	if err != nil {
+		return err
+	}
+
+	if rs.provenance == nil {
+		trie.InsertRoute(ipBits)
+		return nil
 	}
 
+	rs.provenance.record(trie, ipBits, Input{Source: source, Prefix: ipPrefix.Masked()})
+
 	return nil
 }
 
-func ipBitsForIPPrefix(ipPrefix netip.Prefix) (bitslice.BitSlice, error) {
-	ipBytes, err := ipPrefix.Addr().MarshalBinary()
+// InsertAddr adds an IP to the summary. As with InsertFromString, IPs with zones are rejected.
+func (rs *RouteSum) InsertAddr(ip netip.Addr) error {
+	if !ip.IsValid() {
+		return newInvalidInputErr(ip.String(), ReasonInvalidIP, "not a valid IP")
+	}
+	if ip.Zone() != "" {
+		return newInvalidInputErr(ip.String(), ReasonInvalidIP, "IPs with zones are not supported")
+	}
+
+	return rs.InsertPrefix(netip.PrefixFrom(ip, ip.BitLen()))
+}
+
+// RemoveFromString removes either a string-formatted network or IP from the summary. Any part of the summary covered
+// by it is removed, and summarized networks that partially overlap it are split into the networks that remain.
+func (rs *RouteSum) RemoveFromString(s string) error {
+	ipPrefix, err := parsePrefix(s)
 	if err != nil {
-		return nil, errors.Wrapf(err, "express %s as bytes", ipPrefix.Addr().String())
+		return err
 	}
 
-	ipBits, err := bitslice.NewFromBytes(ipBytes)
+	return rs.RemovePrefix(ipPrefix)
+}
+
+// RemovePrefix removes a network from the summary, as RemoveFromString does.
+func (rs *RouteSum) RemovePrefix(ipPrefix netip.Prefix) error {
+	trie, ipBits, err := rs.trieAndBitsForIPPrefix(ipPrefix)
 	if err != nil {
-		return nil, fmt.Errorf("express %s as bits: %w", ipPrefix.Addr().String(), err)
+		return err
 	}
 
-	return ipBits[:ipPrefix.Bits()], nil
+	trie.RemoveRoute(ipBits)
+
+	return nil
 }
 
-func ipBitsForIP(ip netip.Addr) (bitslice.BitSlice, error) {
-	ipBytes, err := ip.MarshalBinary()
+// Contains reports whether an IP is covered by the summary.
+func (rs *RouteSum) Contains(ip netip.Addr) bool {
+	_, ok := rs.Lookup(ip)
+	return ok
+}
+
+// ContainsPrefix reports whether a network is entirely covered by the summary.
+func (rs *RouteSum) ContainsPrefix(ipPrefix netip.Prefix) bool {
+	trie, ipBits, err := rs.trieAndBitsForIPPrefix(ipPrefix)
 	if err != nil {
-		return nil, errors.Wrapf(err, "express %s as bytes", ip.String())
+		return false
 	}
 
-	ipBits, err := bitslice.NewFromBytes(ipBytes)
+	return trie.Contains(ipBits)
+}
+
+// Lookup returns the summarized network covering an IP, if there is one. The network always contains the IP: an IPv4
+// network covering an IPv6 address the RouteSum unmaps is given embedded the way the address is, whatever the
+// RouteSum's output embedding.
+func (rs *RouteSum) Lookup(ip netip.Addr) (netip.Prefix, bool) {
+	if !ip.IsValid() {
+		return netip.Prefix{}, false
+	}
+
+	trie, ipBits, err := rs.trieAndBitsForIPPrefix(netip.PrefixFrom(ip, ip.BitLen()))
 	if err != nil {
-		return nil, fmt.Errorf("express %s as bits: %w", ip.String(), err)
+		return netip.Prefix{}, false
+	}
+
+	coveringBits, ok := trie.Lookup(ipBits)
+	if !ok {
+		return netip.Prefix{}, false
 	}
 
-	return ipBits, nil
+	if trie == rs.ipv4 {
+		// IPv4 networks are given in the form ip is, rather than as they're output, so that they contain it.
+		return embed(ipv4PrefixFromBits(coveringBits), rs.unmappedEmbedding(ip)), true
+	}
+
+	return ipv6PrefixFromBits(coveringBits), true
+}
+
+// Union returns a new RouteSum summarizing everything in either rs or other.
+func (rs *RouteSum) Union(other *RouteSum) *RouteSum {
+	return rs.withTries(rs.ipv4.Union(other.ipv4), rs.ipv6.Union(other.ipv6))
+}
+
+// Intersect returns a new RouteSum summarizing everything in both rs and other.
+func (rs *RouteSum) Intersect(other *RouteSum) *RouteSum {
+	return rs.withTries(rs.ipv4.Intersect(other.ipv4), rs.ipv6.Intersect(other.ipv6))
+}
+
+// Difference returns a new RouteSum summarizing everything in rs that isn't in other.
+func (rs *RouteSum) Difference(other *RouteSum) *RouteSum {
+	return rs.withTries(rs.ipv4.Difference(other.ipv4), rs.ipv6.Difference(other.ipv6))
+}
+
+// SymmetricDifference returns a new RouteSum summarizing everything in exactly one of rs and other.
+func (rs *RouteSum) SymmetricDifference(other *RouteSum) *RouteSum {
+	return rs.withTries(rs.ipv4.SymmetricDifference(other.ipv4), rs.ipv6.SymmetricDifference(other.ipv6))
+}
+
+// Complement returns a new RouteSum summarizing every IPv4 and IPv6 address not in rs. The complement of an empty
+// RouteSum is therefore 0.0.0.0/0 and ::/0.
+func (rs *RouteSum) Complement() *RouteSum {
+	return rs.withTries(rs.ipv4.Complement(), rs.ipv6.Complement())
+}
+
+// ComplementWithin returns a new RouteSum summarizing every address in a network that isn't in rs.
+func (rs *RouteSum) ComplementWithin(ipPrefix netip.Prefix) (*RouteSum, error) {
+	within := rs.withTries(rstrie.NewRSTrie(), rstrie.NewRSTrie())
+	if err := within.InsertPrefix(ipPrefix); err != nil {
+		return nil, err
+	}
+
+	return within.Difference(rs), nil
+}
+
+// SummarizeTo reduces the summary to at most maxEntries networks and IPs, at the cost of covering addresses that were
+// never inserted. It repeatedly merges sibling networks into their parent network, choosing the merge that adds the
+// fewest addresses, until the summary fits. Addresses are counted the same way in both address families, so merges of
+// IPv4 networks, which add fewer addresses, tend to be chosen before merges of IPv6 networks. SummarizeTo returns the
+// number of addresses added. If maxEntries is too small to hold even one network per address family in use, the
+// summary is left unchanged and an error is returned.
+func (rs *RouteSum) SummarizeTo(maxEntries int) (*big.Int, error) {
+	overcovered, err := rstrie.SummarizeTo(
+		maxEntries,
+		rstrie.SizedTrie{Trie: rs.ipv4, BitLen: 8 * 4},
+		rstrie.SizedTrie{Trie: rs.ipv6, BitLen: 8 * 16},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("summarize to %d entries: %w", maxEntries, err)
+	}
+
+	return overcovered, nil
+}
+
+// OvercoveredPrefix describes a network made by lossy summarization, and the number of addresses it covers that
+// weren't in the summary before.
+type OvercoveredPrefix struct {
+	Prefix      netip.Prefix
+	Overcovered *big.Int
+}
+
+// SummarizeWithinRatio replaces groups of networks and IPs with a single covering network wherever the addresses
+// added make up no more than maxRatio of the covering network's addresses. For example, with a maxRatio of 0.25,
+// 192.0.2.0/24 and 192.0.3.0/25 are replaced with 192.0.2.0/23, as the 128 addresses added are a quarter of the
+// network. The largest qualifying networks are used. The networks made are returned in the same order as EachPrefix,
+// along with the number of addresses each added. maxRatio must be between 0 and 1.
+func (rs *RouteSum) SummarizeWithinRatio(maxRatio float64) ([]OvercoveredPrefix, error) {
+	if !(maxRatio >= 0 && maxRatio <= 1) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRatio, maxRatio)
+	}
+
+	merged := []OvercoveredPrefix{}
+	for _, route := range rs.ipv4.SummarizeWithinRatio(8*4, maxRatio) {
+		merged = append(merged, OvercoveredPrefix{
+			Prefix:      rs.render(ipv4PrefixFromBits(route.Route)),
+			Overcovered: route.Overcovered,
+		})
+	}
+	for _, route := range rs.ipv6.SummarizeWithinRatio(8*16, maxRatio) {
+		merged = append(merged, OvercoveredPrefix{
+			Prefix:      ipv6PrefixFromBits(route.Route),
+			Overcovered: route.Overcovered,
+		})
+	}
+	if rs.embeddedOutput != 0 {
+		// IPv4 networks output embedded in IPv6 belong among the IPv6 networks.
+		slices.SortFunc(merged, func(a, b OvercoveredPrefix) int {
+			return a.Prefix.Addr().Compare(b.Prefix.Addr())
+		})
+	}
+
+	return merged, nil
+}
+
+// parsePrefix parses a string-formatted network or IP. IPs are returned as single-address networks.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if isMasked(s) {
+		return parseMasked(s)
+	}
+
+	if strings.Contains(s, "/") {
+		ipPrefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, wrapInvalidInputErr(s, ReasonInvalidNetwork, err)
+		}
+		if !ipPrefix.IsValid() {
+			return netip.Prefix{}, newInvalidInputErr(s, ReasonInvalidNetwork, "not valid CIDR")
+		}
+
+		return ipPrefix, nil
+	}
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, wrapInvalidInputErr(s, ReasonInvalidIP, err)
+	}
+	if ip.Zone() != "" {
+		return netip.Prefix{}, newInvalidInputErr(s, ReasonInvalidIP, "IPs with zones are not supported")
+	}
+
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// trieAndBitsForIPPrefix returns the trie a network belongs in, once unmapped, and its bits there.
+func (rs *RouteSum) trieAndBitsForIPPrefix(ipPrefix netip.Prefix) (*rstrie.RSTrie, bitslice.BitSlice, error) {
+	if !ipPrefix.IsValid() {
+		return nil, bitslice.BitSlice{}, newInvalidInputErr(ipPrefix.String(), ReasonInvalidNetwork, "not valid CIDR")
+	}
+	ipPrefix = rs.unmap(ipPrefix)
+
+	ipBits, err := ipBitsForIPPrefix(ipPrefix)
+	if err != nil {
+		return nil, bitslice.BitSlice{}, err
+	}
+
+	if ipPrefix.Addr().Is4() {
+		return rs.ipv4, ipBits, nil
+	}
+
+	return rs.ipv6, ipBits, nil
+}
+
+func ipBitsForIPPrefix(ipPrefix netip.Prefix) (bitslice.BitSlice, error) {
+	// Reading the address into an array, rather than marshaling it, saves an allocation per insert.
+	ipBytes := ipPrefix.Addr().As16()
+	if ipPrefix.Addr().Is4() {
+		copy(ipBytes[:], ipBytes[12:])
+	}
+
+	ipBits, err := bitslice.NewFromBytes(ipBytes[:ipPrefix.Addr().BitLen()/8])
+	if err != nil {
+		return bitslice.BitSlice{}, fmt.Errorf("express %s as bits: %w", ipPrefix.Addr().String(), err)
+	}
+
+	return ipBits.Prefix(ipPrefix.Bits()), nil
 }
 
 // SummaryStrings returns a summary of all received routes as a string slice.
@@ -106,41 +406,85 @@ func (rs *RouteSum) SummaryStrings() []string {
 	return slices.Collect(rs.Each())
 }
 
-// Each returns an iterator that returns each IP or prefix stored.
+// Each returns an iterator that returns each IP or prefix stored. The order is guaranteed: IPv4 before IPv6, and
+// within each, ascending by address. Stored networks never overlap, so no two share an address to be ordered by
+// prefix length.
 func (rs *RouteSum) Each() iter.Seq[string] {
-	return func(yield func(string) bool) {
-		for bits := range rs.ipv4.Each() {
-			ip := ipv4FromBits(bits)
-
-			if len(bits) == 8*4 {
-				if !yield(ip.String()) {
-					return
-				}
-			} else {
-				prefix := netip.PrefixFrom(ip, len(bits))
-				if !yield(prefix.String()) {
-					return
-				}
+	return eachString(rs.EachPrefix())
+}
+
+// EachDescending returns an iterator that returns each IP or prefix stored, in exactly the reverse of Each's order.
+func (rs *RouteSum) EachDescending() iter.Seq[string] {
+	return eachString(rs.EachPrefixDescending())
+}
+
+// EachPrefix returns an iterator that returns each prefix stored. IPs are returned as single-address prefixes. The
+// order is the same as Each's.
+func (rs *RouteSum) EachPrefix() iter.Seq[netip.Prefix] {
+	return func(yield func(netip.Prefix) bool) {
+		ipv4, ipv6 := rs.outputTries()
+		for bits := range ipv4.Each() {
+			if !yield(ipv4PrefixFromBits(bits)) {
+				return
 			}
 		}
 
-		for bits := range rs.ipv6.Each() {
-			ip := ipv6FromBits(bits)
-
-			if len(bits) == 8*16 {
-				if !yield(ip.String()) {
-					return
-				}
-			} else {
-				prefix := netip.PrefixFrom(ip, len(bits))
-				if !yield(prefix.String()) {
-					return
-				}
+		for bits := range ipv6.Each() {
+			if !yield(ipv6PrefixFromBits(bits)) {
+				return
 			}
 		}
 	}
 }
 
+// EachPrefixDescending returns an iterator that returns each prefix stored, in exactly the reverse of EachPrefix's
+// order.
+func (rs *RouteSum) EachPrefixDescending() iter.Seq[netip.Prefix] {
+	return func(yield func(netip.Prefix) bool) {
+		ipv4, ipv6 := rs.outputTries()
+		for bits := range ipv6.EachDescending() {
+			if !yield(ipv6PrefixFromBits(bits)) {
+				return
+			}
+		}
+
+		for bits := range ipv4.EachDescending() {
+			if !yield(ipv4PrefixFromBits(bits)) {
+				return
+			}
+		}
+	}
+}
+
+// eachString formats prefixes as strings, with single-address prefixes formatted as IPs.
+func eachString(prefixes iter.Seq[netip.Prefix]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for prefix := range prefixes {
+			if !yield(PrefixString(prefix)) {
+				return
+			}
+		}
+	}
+}
+
+// PrefixString formats a prefix as Each does: in CIDR notation, except that single-address prefixes are formatted as
+// IPs.
+func PrefixString(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+
+	return p.String()
+}
+
+func ipv4PrefixFromBits(bits bitslice.BitSlice) netip.Prefix {
+	return netip.PrefixFrom(ipv4FromBits(bits), bits.Len())
+}
+
+func ipv6PrefixFromBits(bits bitslice.BitSlice) netip.Prefix {
+	return netip.PrefixFrom(ipv6FromBits(bits), bits.Len())
+}
+
 func ipv4FromBits(bits bitslice.BitSlice) netip.Addr {
 	bytes := bits.ToBytes(4)
 	byteArray := [4]byte{}
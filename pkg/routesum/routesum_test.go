@@ -1,9 +1,17 @@
 package routesum
 
 import (
+	"errors"
+	"math"
+	"math/big"
+	"net/netip"
 	"regexp"
+	"runtime"
+	"slices"
 	"testing"
 
+	"github.com/PatrickCronin/routesum/pkg/routesum/internal/corpus"
+	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -455,3 +463,529 @@ func TestSummarize(t *testing.T) { //nolint: funlen
 		})
 	}
 }
+
+func TestRemove(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name     string
+		input    []string
+		remove   []string
+		expected []string
+	}{
+		{
+			name:     "removing from an empty summary",
+			input:    []string{},
+			remove:   []string{"192.0.2.0/24", "2001:db8::"},
+			expected: []string(nil),
+		},
+		{
+			name:     "removing an exact match",
+			input:    []string{"192.0.2.0/24", "::ffff:192.0.2.0/120", "2001:db8::1"},
+			remove:   []string{"192.0.2.0/24", "::ffff:192.0.2.0/120", "2001:db8::1"},
+			expected: []string(nil),
+		},
+		{
+			name:     "removing a covering network",
+			input:    []string{"192.0.2.1", "192.0.2.64/26", "2001:db8::/64"},
+			remove:   []string{"192.0.2.0/24", "2001:db8::/48"},
+			expected: []string(nil),
+		},
+		{
+			name:   "removing an IP splits its covering network",
+			input:  []string{"10.0.0.0/24"},
+			remove: []string{"10.0.0.5"},
+			expected: []string{
+				"10.0.0.0/30",
+				"10.0.0.4",
+				"10.0.0.6/31",
+				"10.0.0.8/29",
+				"10.0.0.16/28",
+				"10.0.0.32/27",
+				"10.0.0.64/26",
+				"10.0.0.128/25",
+			},
+		},
+		{
+			name:   "removing a network splits its covering network",
+			input:  []string{"2001:db8::/32"},
+			remove: []string{"2001:db8:8000::/34"},
+			expected: []string{
+				"2001:db8::/33",
+				"2001:db8:c000::/34",
+			},
+		},
+		{
+			name:     "address families are kept apart",
+			input:    []string{"192.0.2.0/24", "::ffff:192.0.2.0/120"},
+			remove:   []string{"::ffff:192.0.2.0/120"},
+			expected: []string{"192.0.2.0/24"},
+		},
+		{
+			name:     "removed space can be summarized again",
+			input:    []string{"192.0.2.0/24", "192.0.2.128/25"},
+			remove:   []string{"192.0.2.128/25"},
+			expected: []string{"192.0.2.0/25"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rs := NewRouteSum()
+			for _, str := range test.input {
+				require.NoError(t, rs.InsertFromString(str))
+			}
+			for _, str := range test.remove {
+				require.NoError(t, rs.RemoveFromString(str))
+			}
+			assert.Equal(t, test.expected, rs.SummaryStrings(), "got expected summary")
+		})
+	}
+
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertFromString("198.51.100.0/24"))
+	require.NoError(t, rs.RemovePrefix(netip.MustParsePrefix("198.51.100.0/25")))
+	assert.Equal(t, []string{"198.51.100.128/25"}, rs.SummaryStrings(), "RemovePrefix removes the network")
+
+	err := rs.RemovePrefix(netip.Prefix{})
+	assert.Error(t, err, "RemovePrefix rejects an invalid prefix")
+
+	err = rs.RemoveFromString("not an IP")
+	if assert.Error(t, err) {
+		assert.Regexp(t, regexp.MustCompile(`ParseAddr`), err.Error())
+	}
+}
+
+func TestNetIP(t *testing.T) {
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertPrefix(netip.MustParsePrefix("192.0.2.0/25")))
+	require.NoError(t, rs.InsertPrefix(netip.MustParsePrefix("192.0.2.128/25")))
+	require.NoError(t, rs.InsertPrefix(netip.MustParsePrefix("2001:db8::/32")))
+	require.NoError(t, rs.InsertAddr(netip.MustParseAddr("198.51.100.7")))
+	require.NoError(t, rs.InsertAddr(netip.MustParseAddr("::ffff:198.51.100.7")))
+
+	assert.Equal(
+		t,
+		[]netip.Prefix{
+			netip.MustParsePrefix("192.0.2.0/24"),
+			netip.MustParsePrefix("198.51.100.7/32"),
+			netip.MustParsePrefix("::ffff:198.51.100.7/128"),
+			netip.MustParsePrefix("2001:db8::/32"),
+		},
+		slices.Collect(rs.EachPrefix()),
+		"EachPrefix returns the summary as prefixes",
+	)
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/24", "198.51.100.7", "::ffff:198.51.100.7", "2001:db8::/32"},
+		slices.Collect(rs.Each()),
+		"Each formats single-address prefixes as IPs",
+	)
+
+	assert.Error(t, rs.InsertPrefix(netip.Prefix{}), "InsertPrefix rejects an invalid prefix")
+	assert.Error(t, rs.InsertAddr(netip.Addr{}), "InsertAddr rejects an invalid IP")
+	assert.Error(t, rs.InsertFromString("fe80::1%eth0"), "zoned IPs are rejected")
+	zoneErr := rs.InsertAddr(netip.MustParseAddr("fe80::1%eth0"))
+	if assert.Error(t, zoneErr, "InsertAddr rejects zoned IPs") {
+		assert.Equal(t, rs.InsertFromString("fe80::1%eth0").Error(), zoneErr.Error(), "as InsertFromString does")
+	}
+	assert.Len(t, slices.Collect(rs.EachPrefix()), 4, "nothing was added")
+}
+
+func TestPrefixString(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.0/24":        "192.0.2.0/24",
+		"192.0.2.1/32":        "192.0.2.1",
+		"0.0.0.0/0":           "0.0.0.0/0",
+		"2001:db8::/32":       "2001:db8::/32",
+		"2001:db8::1/128":     "2001:db8::1",
+		"::ffff:c000:200/128": "::ffff:192.0.2.0",
+	}
+	for prefix, expected := range tests {
+		assert.Equal(t, expected, PrefixString(netip.MustParsePrefix(prefix)), prefix)
+	}
+}
+
+func TestLookup(t *testing.T) { //nolint: funlen
+	rs := NewRouteSum()
+	for _, s := range []string{
+		"198.51.100.0/25",
+		"198.51.100.128/25",
+		"192.0.2.9",
+		"::ffff:192.0.2.0/120",
+		"2001:db8::/32",
+	} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{ip: "198.51.100.7", expected: "198.51.100.0/24"},
+		{ip: "198.51.100.255", expected: "198.51.100.0/24"},
+		{ip: "192.0.2.9", expected: "192.0.2.9/32"},
+		{ip: "192.0.2.8", expected: ""},
+		{ip: "203.0.113.1", expected: ""},
+		{ip: "::ffff:192.0.2.8", expected: "::ffff:192.0.2.0/120"},
+		{ip: "::ffff:198.51.100.7", expected: ""},
+		{ip: "2001:db8:ffff::1", expected: "2001:db8::/32"},
+		{ip: "2001:db9::", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			ip := netip.MustParseAddr(test.ip)
+			covering, found := rs.Lookup(ip)
+			if test.expected == "" {
+				assert.False(t, found, "not found")
+				assert.False(t, rs.Contains(ip), "not contained")
+				return
+			}
+			assert.True(t, found, "found")
+			assert.True(t, rs.Contains(ip), "contained")
+			assert.Equal(t, netip.MustParsePrefix(test.expected), covering, "got expected covering network")
+		})
+	}
+
+	prefixTests := []struct {
+		prefix   string
+		expected bool
+	}{
+		{prefix: "198.51.100.0/24", expected: true},
+		{prefix: "198.51.100.64/26", expected: true},
+		{prefix: "198.51.100.0/23", expected: false},
+		{prefix: "192.0.2.9/32", expected: true},
+		{prefix: "192.0.2.8/31", expected: false},
+		{prefix: "::ffff:198.51.100.0/120", expected: false},
+		{prefix: "2001:db8:1::/48", expected: true},
+		{prefix: "2001:db8::/31", expected: false},
+	}
+
+	for _, test := range prefixTests {
+		t.Run(test.prefix, func(t *testing.T) {
+			contained := rs.ContainsPrefix(netip.MustParsePrefix(test.prefix))
+			assert.Equal(t, test.expected, contained, "contained as expected")
+		})
+	}
+
+	assert.False(t, rs.Contains(netip.Addr{}), "an invalid IP isn't contained")
+	assert.False(t, rs.ContainsPrefix(netip.Prefix{}), "an invalid prefix isn't contained")
+}
+
+func TestSetOps(t *testing.T) {
+	a := NewRouteSum()
+	for _, s := range []string{"192.0.2.0/25", "198.51.100.0/24", "2001:db8::/33"} {
+		require.NoError(t, a.InsertFromString(s))
+	}
+	b := NewRouteSum()
+	for _, s := range []string{"192.0.2.128/25", "198.51.100.128/25", "::ffff:198.51.100.0/120", "2001:db8::/32"} {
+		require.NoError(t, b.InsertFromString(s))
+	}
+
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/24", "198.51.100.0/24", "::ffff:198.51.100.0/120", "2001:db8::/32"},
+		a.Union(b).SummaryStrings(),
+		"got expected union",
+	)
+	assert.Equal(
+		t,
+		[]string{"198.51.100.128/25", "2001:db8::/33"},
+		a.Intersect(b).SummaryStrings(),
+		"got expected intersection",
+	)
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/25", "198.51.100.0/25"},
+		a.Difference(b).SummaryStrings(),
+		"got expected difference",
+	)
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/24", "198.51.100.0/25", "::ffff:198.51.100.0/120", "2001:db8:8000::/33"},
+		a.SymmetricDifference(b).SummaryStrings(),
+		"got expected symmetric difference",
+	)
+
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/25", "198.51.100.0/24", "2001:db8::/33"},
+		a.SummaryStrings(),
+		"operands are unchanged",
+	)
+	assert.Equal(t, a.SummaryStrings(), a.Union(a).SummaryStrings(), "a set unioned with itself is unchanged")
+	assert.Equal(t, []string(nil), a.Difference(a).SummaryStrings(), "a set minus itself is empty")
+}
+
+func TestComplement(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"0.0.0.0/0", "::/0"},
+		NewRouteSum().Complement().SummaryStrings(),
+		"the complement of nothing is everything",
+	)
+
+	rs := NewRouteSum()
+	for _, s := range []string{"0.0.0.0/1", "192.0.0.0/2", "::/1"} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+	assert.Equal(
+		t,
+		[]string{"128.0.0.0/2", "8000::/1"},
+		rs.Complement().SummaryStrings(),
+		"got expected complement",
+	)
+
+	within, err := rs.ComplementWithin(netip.MustParsePrefix("128.0.0.0/1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"128.0.0.0/2"}, within.SummaryStrings(), "got expected complement within a network")
+
+	within, err = rs.ComplementWithin(netip.MustParsePrefix("192.0.2.0/24"))
+	require.NoError(t, err)
+	assert.Equal(t, []string(nil), within.SummaryStrings(), "nothing is missing from a covered network")
+
+	_, err = rs.ComplementWithin(netip.Prefix{})
+	assert.Error(t, err, "an invalid network is rejected")
+}
+
+func TestOrder(t *testing.T) {
+	input := []string{
+		"2001:db8:1::/48",
+		"198.51.100.0/24",
+		"::ffff:192.0.2.0/120",
+		"192.0.2.9",
+		"2001:db8::1",
+		"10.0.0.0/8",
+		"192.0.2.0/29",
+		"::1",
+	}
+	ascending := []string{
+		"10.0.0.0/8",
+		"192.0.2.0/29",
+		"192.0.2.9",
+		"198.51.100.0/24",
+		"::1",
+		"::ffff:192.0.2.0/120",
+		"2001:db8::1",
+		"2001:db8:1::/48",
+	}
+
+	rs := NewRouteSum()
+	for _, s := range input {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	assert.Equal(t, ascending, slices.Collect(rs.Each()), "Each is ascending, IPv4 first")
+	assert.True(
+		t,
+		slices.IsSortedFunc(slices.Collect(rs.EachPrefix()), comparePrefixes),
+		"EachPrefix is ascending by address then prefix length",
+	)
+
+	slices.Reverse(ascending)
+	assert.Equal(t, ascending, slices.Collect(rs.EachDescending()), "EachDescending is the reverse of Each")
+
+	descendingPrefixes := slices.Collect(rs.EachPrefixDescending())
+	slices.Reverse(descendingPrefixes)
+	assert.Equal(
+		t,
+		slices.Collect(rs.EachPrefix()),
+		descendingPrefixes,
+		"EachPrefixDescending is the reverse of EachPrefix",
+	)
+}
+
+func comparePrefixes(a, b netip.Prefix) int {
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+
+	return a.Bits() - b.Bits()
+}
+
+func TestSummarizeTo(t *testing.T) {
+	rs := NewRouteSum()
+	for _, s := range []string{
+		"192.0.2.0/25",
+		"192.0.2.128/26",
+		"198.51.100.0",
+		"198.51.100.3",
+		"2001:db8::/33",
+		"2001:db8:c000::/34",
+	} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	overcovered, err := rs.SummarizeTo(6)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(0), overcovered, "nothing is added when the summary fits")
+	assert.Len(t, rs.SummaryStrings(), 6, "summary is unchanged")
+
+	overcovered, err = rs.SummarizeTo(4)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2+64), overcovered, "the cheapest merges are made")
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/24", "198.51.100.0/30", "2001:db8::/33", "2001:db8:c000::/34"},
+		rs.SummaryStrings(),
+		"got expected summary",
+	)
+
+	_, err = rs.SummarizeTo(1)
+	assert.ErrorIs(t, err, rstrie.ErrMaxRoutesTooSmall, "both address families need an entry")
+	assert.Len(t, rs.SummaryStrings(), 4, "summary is unchanged")
+
+	overcovered, err = rs.SummarizeTo(2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.0.0/5", "2001:db8::/32"}, rs.SummaryStrings(), "got expected summary")
+	assert.Equal(t, 1, overcovered.Sign(), "addresses were added")
+}
+
+func TestSummarizeWithinRatio(t *testing.T) {
+	rs := NewRouteSum()
+	for _, s := range []string{
+		"192.0.2.0/24",
+		"192.0.3.0/25",
+		"198.51.100.1",
+		"2001:db8::/33",
+		"2001:db8:8000::/34",
+		"2001:db8:c000::/35",
+	} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	merged, err := rs.SummarizeWithinRatio(0.25)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]OvercoveredPrefix{
+			{Prefix: netip.MustParsePrefix("192.0.2.0/23"), Overcovered: big.NewInt(128)},
+			{Prefix: netip.MustParsePrefix("2001:db8::/32"), Overcovered: new(big.Int).Lsh(big.NewInt(1), 93)},
+		},
+		merged,
+		"got expected merged networks",
+	)
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/23", "198.51.100.1", "2001:db8::/32"},
+		rs.SummaryStrings(),
+		"got expected summary",
+	)
+
+	merged, err = rs.SummarizeWithinRatio(0)
+	require.NoError(t, err)
+	assert.Empty(t, merged, "nothing is merged with a zero ratio")
+
+	for _, ratio := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err = rs.SummarizeWithinRatio(ratio)
+		assert.ErrorIs(t, err, ErrInvalidRatio, "ratio %v is rejected", ratio)
+	}
+}
+
+// benchmarkCorpusSize is the number of networks and IPs each benchmark workload summarizes.
+func TestHostBits(t *testing.T) {
+	rs := NewRouteSum()
+	require.NoError(t, rs.InsertFromString("192.0.2.5/24"))
+	assert.Equal(t, []string{"192.0.2.0/24"}, rs.SummaryStrings(), "host bits are masked off by default")
+
+	rs = NewRouteSum(WithStrict())
+	for _, s := range []string{"192.0.2.5/24", "192.0.2.5 255.255.255.0"} {
+		err := rs.InsertFromString(s)
+		var iIErr *InvalidInputErr
+		if assert.True(t, errors.As(err, &iIErr), "strict mode rejects host bits") {
+			assert.Equal(t, s, iIErr.InvalidValue, "the value is reported as given")
+			assert.Equal(t, "host bits are set; the network is 192.0.2.0/24", iIErr.Reason)
+		}
+	}
+	err := rs.InsertPrefix(netip.MustParsePrefix("2001:db8::1/32"))
+	assert.ErrorContains(t, err, "'2001:db8::1/32' was not understood", "strict mode rejects host bits in prefixes")
+	require.NoError(t, rs.InsertFromString("192.0.2.0/24"))
+	require.NoError(t, rs.InsertFromString("192.0.2.5"))
+	require.NoError(t, rs.InsertFromString("198.51.100.3-198.51.100.4"))
+	assert.Equal(
+		t,
+		[]string{"192.0.2.0/24", "198.51.100.3", "198.51.100.4"},
+		rs.SummaryStrings(),
+		"strict mode accepts networks without host bits, IPs and ranges",
+	)
+	assert.Equal(t, int64(3), rs.Stats().Inputs, "rejected networks aren't counted")
+
+	var warnings []Input
+	rs = NewRouteSum(WithHostBitsWarning(func(input Input) {
+		warnings = append(warnings, input)
+	}))
+	require.NoError(t, rs.InsertFromStringWithSource("192.0.2.5/24", Source{File: "feed", Line: 3}))
+	require.NoError(t, rs.InsertFromStringWithSource("198.51.100.0/24", Source{File: "feed", Line: 4}))
+	assert.Equal(
+		t,
+		[]Input{{Source: Source{File: "feed", Line: 3}, Prefix: netip.MustParsePrefix("192.0.2.5/24")}},
+		warnings,
+		"host bits are warned about",
+	)
+	assert.Equal(t, []string{"192.0.2.0/24", "198.51.100.0/24"}, rs.SummaryStrings(), "warned networks are inserted")
+}
+
+const benchmarkCorpusSize = 100_000
+
+// BenchmarkInsertPrefix summarizes each corpus workload, reporting the heap retained by the summary as well as the
+// usual allocation counts.
+func BenchmarkInsertPrefix(b *testing.B) {
+	for _, workload := range corpus.Workloads() {
+		for _, family := range corpus.Families() {
+			prefixes := workload.Generate(family, benchmarkCorpusSize)
+
+			b.Run(workload.Name+"/"+family.Name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				var retained uint64
+				for range b.N {
+					var before, after runtime.MemStats
+					b.StopTimer()
+					runtime.GC()
+					runtime.ReadMemStats(&before)
+					b.StartTimer()
+
+					rs := NewRouteSum()
+					for _, p := range prefixes {
+						if err := rs.InsertPrefix(p); err != nil {
+							b.Fatal(err)
+						}
+					}
+
+					b.StopTimer()
+					runtime.GC()
+					runtime.ReadMemStats(&after)
+					b.StartTimer()
+					retained += after.HeapAlloc - before.HeapAlloc
+					runtime.KeepAlive(rs)
+				}
+				b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+			})
+		}
+	}
+}
+
+// BenchmarkEachPrefix iterates over the summary of each corpus workload.
+func BenchmarkEachPrefix(b *testing.B) {
+	for _, workload := range corpus.Workloads() {
+		for _, family := range corpus.Families() {
+			rs := NewRouteSum()
+			for _, p := range workload.Generate(family, benchmarkCorpusSize) {
+				if err := rs.InsertPrefix(p); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.Run(workload.Name+"/"+family.Name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				for range b.N {
+					count := 0
+					for range rs.EachPrefix() {
+						count++
+					}
+					runtime.KeepAlive(count)
+				}
+			})
+		}
+	}
+}
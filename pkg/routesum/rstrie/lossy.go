@@ -0,0 +1,229 @@
+package rstrie
+
+import (
+	"container/heap"
+	"errors"
+	"math/big"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+)
+
+// ErrMaxRoutesTooSmall is returned when a trie can't be summarized into as few routes as requested. Every non-empty
+// trie needs at least one route.
+var ErrMaxRoutesTooSmall = errors.New("maximum number of routes is too small")
+
+// SizedTrie pairs an RSTrie with the bit length of the addresses it stores, which is needed to count the addresses
+// its routes cover.
+type SizedTrie struct {
+	Trie   *RSTrie
+	BitLen int
+}
+
+// SummarizeTo merges routes in the given tries until at most maxRoutes remain among them, and returns how many
+// addresses were added to the tries by doing so. Each merge replaces two sibling routes with their parent, and merges
+// adding the fewest addresses are made first, whichever trie they're in. If maxRoutes is less than the number of
+// non-empty tries, no trie is modified and ErrMaxRoutesTooSmall is returned.
+func SummarizeTo(maxRoutes int, tries ...SizedTrie) (*big.Int, error) {
+	for _, st := range tries {
+		st.Trie.mu.Lock()
+		defer st.Trie.mu.Unlock()
+	}
+
+	numRoutes := 0
+	numNonEmpty := 0
+	candidates := &mergeHeap{
+		candidates: nil,
+		found:      0,
+	}
+	for _, st := range tries {
+		if st.Trie.root == nil {
+			continue
+		}
+
+		numNonEmpty++
+		numRoutes += st.Trie.root.findMergeCandidates(st.BitLen, nil, 0, candidates)
+	}
+
+	if maxRoutes < numNonEmpty {
+		return nil, ErrMaxRoutesTooSmall
+	}
+
+	overcovered := new(big.Int)
+	heap.Init(candidates)
+	for candidates.Len() > 0 {
+		// Once the routes fit, we only continue merging siblings that complete their parent's subtrie, as these add
+		// no addresses and keep the trie in its most summarized form.
+		if numRoutes <= maxRoutes && candidates.candidates[0].cost.Sign() > 0 {
+			break
+		}
+
+		c := heap.Pop(candidates).(*mergeCandidate)
+		c.n.children = nil
+		numRoutes--
+		overcovered.Add(overcovered, c.cost)
+
+		if p := c.parent; p != nil {
+			p.leafChildren++
+			if p.leafChildren == 2 {
+				p.cost = p.mergeCost()
+				heap.Push(candidates, p)
+			}
+		}
+	}
+
+	return overcovered, nil
+}
+
+// OvercoveredRoute describes a route made by lossy summarization, and the number of addresses it covers that weren't
+// covered before.
+type OvercoveredRoute struct {
+	Route       bitslice.BitSlice
+	Overcovered *big.Int
+}
+
+// SummarizeWithinRatio merges each subtrie into a single route if doing so adds addresses making up no more than
+// maxRatio of the addresses the route covers. The largest such subtries are merged, so a merged route is never itself
+// part of another. bitLen is the bit length of the addresses stored in the trie, and maxRatio must not be NaN. The
+// merged routes are returned in ascending order.
+func (t *RSTrie) SummarizeWithinRatio(bitLen int, maxRatio float64) []OvercoveredRoute {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.root == nil {
+		return nil
+	}
+
+	_, merged := t.root.summarizeWithinRatio(bitLen, maxRatio, bitslice.BitSlice{})
+	return merged
+}
+
+// summarizeWithinRatio merges the subtrie rooted at n as SummarizeWithinRatio describes, given the bits leading to n.
+// It returns how many addresses the subtrie covered before any merges, and the routes merged.
+func (n *node) summarizeWithinRatio(
+	bitLen int,
+	maxRatio float64,
+	precedingRouteBits bitslice.BitSlice,
+) (*big.Int, []OvercoveredRoute) {
+	routeBits := precedingRouteBits.Append(n.bits)
+	covers := addressCount(bitLen, routeBits.Len())
+	if n.isLeaf() {
+		return covers, nil
+	}
+
+	covered := new(big.Int)
+	var merged []OvercoveredRoute
+	for _, child := range n.children {
+		childCovered, childMerged := child.summarizeWithinRatio(bitLen, maxRatio, routeBits)
+		covered.Add(covered, childCovered)
+		merged = append(merged, childMerged...)
+	}
+
+	overcovered := new(big.Int).Sub(covers, covered)
+	allowed := new(big.Float).Mul(new(big.Float).SetInt(covers), big.NewFloat(maxRatio))
+	if new(big.Float).SetInt(overcovered).Cmp(allowed) <= 0 {
+		n.children = nil
+		merged = []OvercoveredRoute{{
+			Route:       routeBits,
+			Overcovered: overcovered,
+		}}
+	}
+
+	return covered, merged
+}
+
+// mergeCandidate tracks an internal node that might be merged into a leaf.
+type mergeCandidate struct {
+	n            *node
+	parent       *mergeCandidate
+	bitLen       int
+	depth        int
+	leafChildren int
+	cost         *big.Int
+
+	// seq orders candidates by when they were found, which for a depth-first search of tries given in order is
+	// also their order by trie and then by address.
+	seq int
+}
+
+// findMergeCandidates records a merge candidate for each internal node at or below n, adding those whose children
+// are both leaves to the heap, and returns the number of leaves found.
+func (n *node) findMergeCandidates(bitLen int, parent *mergeCandidate, depth int, candidates *mergeHeap) int {
+	depth += n.bits.Len()
+	if n.isLeaf() {
+		return 1
+	}
+
+	c := &mergeCandidate{
+		n:            n,
+		parent:       parent,
+		bitLen:       bitLen,
+		depth:        depth,
+		leafChildren: 0,
+		cost:         nil,
+		seq:          candidates.found,
+	}
+	candidates.found++
+
+	numLeaves := 0
+	for _, child := range n.children {
+		if child.isLeaf() {
+			c.leafChildren++
+		}
+		numLeaves += child.findMergeCandidates(bitLen, c, depth, candidates)
+	}
+
+	if c.leafChildren == 2 {
+		c.cost = c.mergeCost()
+		candidates.candidates = append(candidates.candidates, c)
+	}
+
+	return numLeaves
+}
+
+// mergeCost returns the number of addresses that merging the candidate's leaf children into it would add.
+func (c *mergeCandidate) mergeCost() *big.Int {
+	cost := addressCount(c.bitLen, c.depth)
+	for _, child := range c.n.children {
+		cost.Sub(cost, addressCount(c.bitLen, c.depth+child.bits.Len()))
+	}
+
+	return cost
+}
+
+// addressCount returns the number of addresses covered by a route of routeLen bits.
+func addressCount(bitLen, routeLen int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(bitLen-routeLen)) //nolint: gosec
+}
+
+// mergeHeap is a min-heap of merge candidates by cost. Ties go to the candidate found first, so that the result is
+// deterministic.
+type mergeHeap struct {
+	candidates []*mergeCandidate
+	found      int
+}
+
+func (h *mergeHeap) Len() int { return len(h.candidates) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if c := h.candidates[i].cost.Cmp(h.candidates[j].cost); c != 0 {
+		return c < 0
+	}
+
+	return h.candidates[i].seq < h.candidates[j].seq
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.candidates[i], h.candidates[j] = h.candidates[j], h.candidates[i]
+}
+
+func (h *mergeHeap) Push(x any) {
+	h.candidates = append(h.candidates, x.(*mergeCandidate))
+}
+
+func (h *mergeHeap) Pop() any {
+	last := len(h.candidates) - 1
+	c := h.candidates[last]
+	h.candidates = h.candidates[:last]
+
+	return c
+}
@@ -0,0 +1,205 @@
+package rstrie
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeTo(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name                string
+		routes              []bitslice.BitSlice
+		maxRoutes           int
+		expected            []bitslice.BitSlice
+		expectedOvercovered int64
+	}{
+		{
+			name:                "routes already fit",
+			routes:              []bitslice.BitSlice{bitslice.New(0, 0, 0, 0), bitslice.New(1, 1, 1, 1)},
+			maxRoutes:           2,
+			expected:            []bitslice.BitSlice{bitslice.New(0, 0, 0, 0), bitslice.New(1, 1, 1, 1)},
+			expectedOvercovered: 0,
+		},
+		{
+			name: "cheapest merge is made",
+			routes: []bitslice.BitSlice{
+				bitslice.New(0, 0, 0, 0),
+				bitslice.New(0, 0, 1, 0),
+				bitslice.New(1, 1, 1, 1),
+			},
+			maxRoutes:           2,
+			expected:            []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1, 1, 1)},
+			expectedOvercovered: 2,
+		},
+		{
+			name: "merges cascade",
+			routes: []bitslice.BitSlice{
+				bitslice.New(0, 0, 0, 0),
+				bitslice.New(0, 0, 1, 0),
+				bitslice.New(1, 1, 1, 1),
+			},
+			maxRoutes:           1,
+			expected:            []bitslice.BitSlice{bitslice.New()},
+			expectedOvercovered: 13,
+		},
+		{
+			name:                "merges adding no addresses are always made",
+			routes:              []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 0), bitslice.New(1)},
+			maxRoutes:           2,
+			expected:            []bitslice.BitSlice{bitslice.New()},
+			expectedOvercovered: 2,
+		},
+		{
+			name:                "empty trie",
+			routes:              nil,
+			maxRoutes:           0,
+			expected:            nil,
+			expectedOvercovered: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trie := newRSTrieFromRoutes(test.routes)
+			overcovered, err := SummarizeTo(test.maxRoutes, SizedTrie{Trie: trie, BitLen: 4})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, trie.Contents(), "got expected routes")
+			assert.Equal(t, big.NewInt(test.expectedOvercovered), overcovered, "got expected over-coverage")
+		})
+	}
+}
+
+func TestSummarizeToSeveralTries(t *testing.T) {
+	narrow := newRSTrieFromRoutes([]bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 1)})
+	wide := newRSTrieFromRoutes([]bitslice.BitSlice{bitslice.New(0, 0, 0, 0, 0, 0), bitslice.New(0, 0, 0, 0, 1, 0)})
+
+	overcovered, err := SummarizeTo(3, SizedTrie{Trie: narrow, BitLen: 3}, SizedTrie{Trie: wide, BitLen: 6})
+	require.NoError(t, err)
+	assert.Equal(t, []bitslice.BitSlice{bitslice.New(0)}, narrow.Contents(), "the cheaper merge was made")
+	assert.Equal(
+		t,
+		[]bitslice.BitSlice{bitslice.New(0, 0, 0, 0, 0, 0), bitslice.New(0, 0, 0, 0, 1, 0)},
+		wide.Contents(),
+		"the more expensive merge was not",
+	)
+	assert.Equal(t, big.NewInt(1), overcovered, "got expected over-coverage")
+
+	_, err = SummarizeTo(1, SizedTrie{Trie: narrow, BitLen: 3}, SizedTrie{Trie: wide, BitLen: 6})
+	assert.ErrorIs(t, err, ErrMaxRoutesTooSmall, "each non-empty trie needs a route")
+	assert.Equal(
+		t,
+		[]bitslice.BitSlice{bitslice.New(0, 0, 0, 0, 0, 0), bitslice.New(0, 0, 0, 0, 1, 0)},
+		wide.Contents(),
+		"nothing is merged when the routes can't fit",
+	)
+}
+
+func TestSummarizeToRandomized(t *testing.T) {
+	const width = 6
+	rng := rand.New(rand.NewPCG(3, 4)) //nolint: gosec
+
+	for range 200 {
+		routes := randomRoutes(rng, width)
+		original := newRSTrieFromRoutes(routes)
+		trie := newRSTrieFromRoutes(routes)
+		maxRoutes := 1 + rng.IntN(4)
+
+		overcovered, err := SummarizeTo(maxRoutes, SizedTrie{Trie: trie, BitLen: width})
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, len(trie.Contents()), maxRoutes, "routes fit")
+
+		added := 0
+		for _, addr := range allRoutes(width) {
+			if original.Contains(addr) {
+				assert.True(t, trie.Contains(addr), "%v is still covered", addr)
+			} else if trie.Contains(addr) {
+				added++
+			}
+		}
+		assert.Equal(t, big.NewInt(int64(added)), overcovered, "over-coverage is counted exactly")
+
+		canonical := newRSTrieFromRoutes(trie.Contents())
+		assert.Equal(t, canonical.root, trie.root, "result is in its most summarized form")
+	}
+}
+
+func TestSummarizeWithinRatio(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name           string
+		routes         []bitslice.BitSlice
+		maxRatio       float64
+		expected       []bitslice.BitSlice
+		expectedMerged []OvercoveredRoute
+	}{
+		{
+			name:           "zero ratio merges nothing",
+			routes:         []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 1)},
+			maxRatio:       0,
+			expected:       []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 1)},
+			expectedMerged: nil,
+		},
+		{
+			name:     "subtrie within ratio is merged",
+			routes:   []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 1)},
+			maxRatio: 0.25,
+			expected: []bitslice.BitSlice{bitslice.New(0)},
+			expectedMerged: []OvercoveredRoute{
+				{Route: bitslice.New(0), Overcovered: big.NewInt(2)},
+			},
+		},
+		{
+			name:           "subtrie beyond ratio is not merged",
+			routes:         []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 1)},
+			maxRatio:       0.24,
+			expected:       []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 1)},
+			expectedMerged: nil,
+		},
+		{
+			name: "the largest subtrie within ratio is merged",
+			routes: []bitslice.BitSlice{
+				bitslice.New(0, 0, 0, 0),
+				bitslice.New(0, 0, 1),
+				bitslice.New(0, 1),
+				bitslice.New(1, 0, 0, 0),
+				bitslice.New(1, 1, 1),
+			},
+			maxRatio: 0.2,
+			expected: []bitslice.BitSlice{bitslice.New(0), bitslice.New(1, 0, 0, 0), bitslice.New(1, 1, 1)},
+			expectedMerged: []OvercoveredRoute{
+				{Route: bitslice.New(0), Overcovered: big.NewInt(1)},
+			},
+		},
+		{
+			name: "the whole trie may be merged",
+			routes: []bitslice.BitSlice{
+				bitslice.New(0, 0, 0, 0),
+				bitslice.New(0, 0, 1),
+				bitslice.New(0, 1),
+				bitslice.New(1, 0, 0, 0),
+				bitslice.New(1, 1, 1),
+			},
+			maxRatio: 0.6,
+			expected: []bitslice.BitSlice{bitslice.New()},
+			expectedMerged: []OvercoveredRoute{
+				{Route: bitslice.New(), Overcovered: big.NewInt(6)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trie := newRSTrieFromRoutes(test.routes)
+			merged := trie.SummarizeWithinRatio(4, test.maxRatio)
+			assert.Equal(t, test.expected, trie.Contents(), "got expected routes")
+			assert.Equal(t, test.expectedMerged, merged, "got expected merged routes")
+		})
+	}
+
+	assert.Nil(t, NewRSTrie().SummarizeWithinRatio(4, 1), "nothing is merged in an empty trie")
+}
@@ -0,0 +1,186 @@
+package rstrie
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+)
+
+// MergeFunc decides whether two sibling routes may be merged into their parent route given their values, and if so,
+// returns the parent route's value. lower is the value of the sibling whose next bit is 0.
+type MergeFunc[V any] func(lower, upper V) (V, bool)
+
+// MergeEqual is a MergeFunc that merges sibling routes only when their values are equal.
+func MergeEqual[V comparable](lower, upper V) (V, bool) {
+	return lower, lower == upper
+}
+
+// Map is a trie like RSTrie that associates a value with each stored route. Where RSTrie always merges sibling routes
+// into their parent, Map does so only when its MergeFunc allows, so routes with different values can be kept apart.
+type Map[V any] struct {
+	mu    sync.RWMutex
+	root  *mapNode[V]
+	merge MergeFunc[V]
+}
+
+type mapNode[V any] struct {
+	children *[2]*mapNode[V]
+	bits     bitslice.BitSlice
+	value    V
+}
+
+// NewMap returns an initialized Map for use, which merges sibling routes as merge allows.
+func NewMap[V any](merge MergeFunc[V]) *Map[V] {
+	return &Map[V]{
+		mu:    sync.RWMutex{},
+		root:  nil,
+		merge: merge,
+	}
+}
+
+// InsertRoute associates value with every address covered by routeBits, replacing any value previously associated
+// with them. Stored routes partially covered by routeBits are split so that what remains keeps its previous value.
+func (m *Map[V]) InsertRoute(routeBits bitslice.BitSlice, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.root = m.insertRoute(m.root, routeBits, value)
+}
+
+// insertRoute returns the node that should take n's place once the route has been inserted into it.
+func (m *Map[V]) insertRoute(n *mapNode[V], remainingRouteBits bitslice.BitSlice, value V) *mapNode[V] {
+	// If there's no node, or the requested route covers the current node, the route simply takes its place.
+	if n == nil ||
+		remainingRouteBits.Len() <= n.bits.Len() && n.bits.HasPrefix(remainingRouteBits) {
+		return newMapLeaf(remainingRouteBits, value)
+	}
+
+	curNodeBitsLen := n.bits.Len()
+	if remainingRouteBits.HasPrefix(n.bits) {
+		// The current node covers the requested route. If it's a leaf, split it around the route.
+		if n.isLeaf() {
+			return m.splitLeaf(n.bits, remainingRouteBits.Suffix(curNodeBitsLen), n.value, value)
+		}
+
+		// Otherwise, we traverse to the correct child.
+		whichChild := remainingRouteBits.Bit(curNodeBitsLen)
+		n.children[whichChild] = m.insertRoute(
+			n.children[whichChild],
+			remainingRouteBits.Suffix(curNodeBitsLen),
+			value,
+		)
+
+		return m.maybeMergeChildren(n)
+	}
+
+	// Otherwise the requested route diverges from the current node, so a new node is needed where they part ways.
+	commonBitsLen := n.bits.CommonPrefixLen(remainingRouteBits)
+	routeNode := newMapLeaf(remainingRouteBits.Suffix(commonBitsLen), value)
+	newNode := &mapNode[V]{
+		children: &[2]*mapNode[V]{},
+		bits:     n.bits.Prefix(commonBitsLen),
+		value:    *new(V),
+	}
+	n.bits = n.bits.Suffix(commonBitsLen)
+	newNode.children[routeNode.bits.Bit(0)] = routeNode
+	newNode.children[n.bits.Bit(0)] = n
+
+	return m.maybeMergeChildren(newNode)
+}
+
+// splitLeaf builds the subtrie that results from associating innerValue with innerBits within a leaf with bits
+// leafBits and value outerValue.
+func (m *Map[V]) splitLeaf(leafBits, innerBits bitslice.BitSlice, outerValue, innerValue V) *mapNode[V] {
+	var inner *mapNode[V]
+	if innerBits.Len() == 1 {
+		inner = newMapLeaf(innerBits, innerValue)
+	} else {
+		inner = m.splitLeaf(innerBits.Prefix(1), innerBits.Suffix(1), outerValue, innerValue)
+	}
+
+	n := &mapNode[V]{
+		children: &[2]*mapNode[V]{},
+		bits:     leafBits,
+		value:    *new(V),
+	}
+	n.children[innerBits.Bit(0)] = inner
+	n.children[1-innerBits.Bit(0)] = newMapLeaf(bitslice.New(1-innerBits.Bit(0)), outerValue)
+
+	return m.maybeMergeChildren(n)
+}
+
+// maybeMergeChildren replaces a node's children with the node itself when together they represent a complete subtrie
+// from the node's perspective, and merge allows it.
+func (m *Map[V]) maybeMergeChildren(n *mapNode[V]) *mapNode[V] {
+	lower, upper := n.children[0], n.children[1]
+	if !lower.isLeaf() || !upper.isLeaf() || lower.bits.Len() != 1 || upper.bits.Len() != 1 {
+		return n
+	}
+
+	value, ok := m.merge(lower.value, upper.value)
+	if !ok {
+		return n
+	}
+
+	return newMapLeaf(n.bits, value)
+}
+
+func newMapLeaf[V any](bits bitslice.BitSlice, value V) *mapNode[V] {
+	return &mapNode[V]{
+		children: nil,
+		bits:     bits,
+		value:    value,
+	}
+}
+
+func (n *mapNode[V]) isLeaf() bool {
+	return n.children == nil
+}
+
+// Lookup returns the stored route covering routeBits and its value, if there is one.
+func (m *Map[V]) Lookup(routeBits bitslice.BitSlice) (bitslice.BitSlice, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matchedBitsLen := 0
+	n := m.root
+	for n != nil && routeBits.Suffix(matchedBitsLen).HasPrefix(n.bits) {
+		matchedBitsLen += n.bits.Len()
+
+		if n.isLeaf() {
+			return routeBits.Prefix(matchedBitsLen), n.value, true
+		}
+
+		// An internal node never has a value of its own, so a route ending here isn't covered.
+		if matchedBitsLen == routeBits.Len() {
+			break
+		}
+
+		n = n.children[routeBits.Bit(matchedBitsLen)]
+	}
+
+	return bitslice.BitSlice{}, *new(V), false
+}
+
+// Each returns an iterator over each stored route and its value, in ascending order of route.
+func (m *Map[V]) Each() iter.Seq2[bitslice.BitSlice, V] {
+	return func(yield func(bitslice.BitSlice, V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if m.root != nil {
+			m.root.each(bitslice.BitSlice{}, yield)
+		}
+	}
+}
+
+// each yields each route below n, given the bits leading to it, and reports whether to continue.
+func (n *mapNode[V]) each(precedingRouteBits bitslice.BitSlice, yield func(bitslice.BitSlice, V) bool) bool {
+	routeBits := precedingRouteBits.Append(n.bits)
+	if n.isLeaf() {
+		return yield(routeBits, n.value)
+	}
+
+	return n.children[0].each(routeBits, yield) && n.children[1].each(routeBits, yield)
+}
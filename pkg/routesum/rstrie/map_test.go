@@ -0,0 +1,170 @@
+package rstrie
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/stretchr/testify/assert"
+)
+
+type routeValue struct {
+	route bitslice.BitSlice
+	value string
+}
+
+func TestMapInsertRoute(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name     string
+		inserts  []routeValue
+		expected []routeValue
+	}{
+		{
+			name:     "siblings with equal values merge",
+			inserts:  []routeValue{{bitslice.New(0, 0), "a"}, {bitslice.New(0, 1), "a"}},
+			expected: []routeValue{{bitslice.New(0), "a"}},
+		},
+		{
+			name:     "siblings with different values don't merge",
+			inserts:  []routeValue{{bitslice.New(0, 0), "a"}, {bitslice.New(0, 1), "b"}},
+			expected: []routeValue{{bitslice.New(0, 0), "a"}, {bitslice.New(0, 1), "b"}},
+		},
+		{
+			name: "merges cascade",
+			inserts: []routeValue{
+				{bitslice.New(1), "a"},
+				{bitslice.New(0, 1), "a"},
+				{bitslice.New(0, 0), "a"},
+			},
+			expected: []routeValue{{bitslice.New(), "a"}},
+		},
+		{
+			name:    "a covered route splits its covering route",
+			inserts: []routeValue{{bitslice.New(0), "a"}, {bitslice.New(0, 1, 1), "b"}},
+			expected: []routeValue{
+				{bitslice.New(0, 0), "a"},
+				{bitslice.New(0, 1, 0), "a"},
+				{bitslice.New(0, 1, 1), "b"},
+			},
+		},
+		{
+			name: "a covered route with the covering route's value changes nothing",
+			inserts: []routeValue{
+				{bitslice.New(0), "a"},
+				{bitslice.New(0, 1, 1), "b"},
+				{bitslice.New(0, 1, 1), "a"},
+			},
+			expected: []routeValue{{bitslice.New(0), "a"}},
+		},
+		{
+			name: "a covering route replaces what it covers",
+			inserts: []routeValue{
+				{bitslice.New(0, 0), "a"},
+				{bitslice.New(0, 1, 1), "b"},
+				{bitslice.New(0), "c"},
+			},
+			expected: []routeValue{{bitslice.New(0), "c"}},
+		},
+		{
+			name:    "diverging routes are kept apart",
+			inserts: []routeValue{{bitslice.New(0, 0, 1), "a"}, {bitslice.New(0, 1), "a"}},
+			expected: []routeValue{
+				{bitslice.New(0, 0, 1), "a"},
+				{bitslice.New(0, 1), "a"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := NewMap(MergeEqual[string])
+			for _, rv := range test.inserts {
+				m.InsertRoute(rv.route, rv.value)
+			}
+
+			assert.Equal(t, test.expected, collectRouteValues(m), "got expected routes and values")
+		})
+	}
+}
+
+func TestMapMergeFunc(t *testing.T) {
+	m := NewMap(func(lower, upper string) (string, bool) {
+		return lower + "+" + upper, len(lower)+len(upper) < 4
+	})
+	m.InsertRoute(bitslice.New(0, 0), "a")
+	m.InsertRoute(bitslice.New(0, 1), "b")
+	m.InsertRoute(bitslice.New(1, 0), "c")
+	m.InsertRoute(bitslice.New(1, 1), "d")
+
+	assert.Equal(
+		t,
+		[]routeValue{{bitslice.New(0), "a+b"}, {bitslice.New(1), "c+d"}},
+		collectRouteValues(m),
+		"siblings merge only as the merge function allows",
+	)
+}
+
+func TestMapLookup(t *testing.T) {
+	m := NewMap(MergeEqual[string])
+	m.InsertRoute(bitslice.New(0, 0), "a")
+	m.InsertRoute(bitslice.New(1, 0, 1), "b")
+	m.InsertRoute(bitslice.New(1, 1), "c")
+
+	route, value, found := m.Lookup(bitslice.New(1, 0, 1, 1))
+	assert.True(t, found, "covered route is found")
+	assert.Equal(t, bitslice.New(1, 0, 1), route, "got covering route")
+	assert.Equal(t, "b", value, "got covering route's value")
+
+	_, _, found = m.Lookup(bitslice.New(1, 0, 0))
+	assert.False(t, found, "uncovered route isn't found")
+
+	_, _, found = m.Lookup(bitslice.New(1))
+	assert.False(t, found, "partially covered route isn't found")
+
+	_, _, found = NewMap(MergeEqual[string]).Lookup(bitslice.New(1))
+	assert.False(t, found, "nothing is found in an empty map")
+}
+
+func TestMapRandomized(t *testing.T) {
+	const width = 5
+	rng := rand.New(rand.NewPCG(5, 6)) //nolint: gosec
+	values := []string{"a", "b", "c"}
+
+	for range 200 {
+		m := NewMap(MergeEqual[string])
+		expected := map[bitslice.BitSlice]string{}
+
+		for _, route := range randomRoutes(rng, width) {
+			value := values[rng.IntN(len(values))]
+			m.InsertRoute(route, value)
+
+			for _, addr := range allRoutes(width) {
+				if addr.HasPrefix(route) {
+					expected[addr] = value
+				}
+			}
+		}
+
+		for _, addr := range allRoutes(width) {
+			_, value, found := m.Lookup(addr)
+			expectedValue, expectedFound := expected[addr]
+			assert.Equal(t, expectedFound, found, "%v is found as expected", addr)
+			assert.Equal(t, expectedValue, value, "%v has the expected value", addr)
+		}
+
+		canonical := NewMap(MergeEqual[string])
+		for route, value := range m.Each() {
+			canonical.InsertRoute(route, value)
+		}
+		assert.Equal(t, canonical.root, m.root, "result is in its most summarized form")
+	}
+}
+
+func collectRouteValues(m *Map[string]) []routeValue {
+	var rvs []routeValue
+	for route, value := range m.Each() {
+		rvs = append(rvs, routeValue{route, value})
+	}
+
+	return rvs
+}
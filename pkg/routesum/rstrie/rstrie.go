@@ -2,7 +2,6 @@
 package rstrie
 
 import (
-	"bytes"
 	"container/list"
 	"iter"
 	"slices"
@@ -58,25 +57,25 @@ func (n *node) isLeaf() bool {
 
 // parent is a **node so that we can change what the parent is pointing to if we need to!
 func (n *node) insertRoute(parent **node, remainingRouteBits bitslice.BitSlice) bool {
-	remainingRouteBitsLen := len(remainingRouteBits)
-	curNodeBitsLen := len(n.bits)
+	remainingRouteBitsLen := remainingRouteBits.Len()
+	curNodeBitsLen := n.bits.Len()
 
 	// Does the requested route cover the current node? If so, update the current node.
-	if remainingRouteBitsLen <= curNodeBitsLen && bytes.HasPrefix(n.bits, remainingRouteBits) {
+	if remainingRouteBitsLen <= curNodeBitsLen && n.bits.HasPrefix(remainingRouteBits) {
 		n.bits = remainingRouteBits
 		n.children = nil
 		return true
 	}
 
-	if curNodeBitsLen <= remainingRouteBitsLen && bytes.HasPrefix(remainingRouteBits, n.bits) {
+	if remainingRouteBits.HasPrefix(n.bits) {
 		// Does the current node cover the requested route? If so, we're done.
 		if n.isLeaf() {
 			return false
 		}
 
 		// Otherwise, we traverse to the correct child.
-		whichChild := remainingRouteBits[curNodeBitsLen]
-		if n.children[whichChild].insertRoute(&n.children[whichChild], remainingRouteBits[curNodeBitsLen:]) {
+		whichChild := remainingRouteBits.Bit(curNodeBitsLen)
+		if n.children[whichChild].insertRoute(&n.children[whichChild], remainingRouteBits.Suffix(curNodeBitsLen)) {
 			return n.maybeRemoveRedundantChildren()
 		}
 
@@ -89,8 +88,8 @@ func (n *node) insertRoute(parent **node, remainingRouteBits bitslice.BitSlice)
 	// just update the current node, instead of allocating new nodes and optimizing them away immediately after.
 	if n.isLeaf() &&
 		curNodeBitsLen == remainingRouteBitsLen &&
-		commonPrefixLen(n.bits, remainingRouteBits) == len(n.bits)-1 {
-		n.bits = n.bits[:len(n.bits)-1]
+		n.bits.CommonPrefixLen(remainingRouteBits) == curNodeBitsLen-1 {
+		n.bits = n.bits.Prefix(curNodeBitsLen - 1)
 		n.children = nil
 		return true
 	}
@@ -99,34 +98,22 @@ func (n *node) insertRoute(parent **node, remainingRouteBits bitslice.BitSlice)
 	return n.maybeRemoveRedundantChildren()
 }
 
-func commonPrefixLen(a, b bitslice.BitSlice) int {
-	i := 0
-	maxLen := min(len(a), len(b))
-	for ; i < maxLen; i++ {
-		if a[i] != b[i] {
-			break
-		}
-	}
-
-	return i
-}
-
 func splitNodeForRoute(oldNode *node, routeBits bitslice.BitSlice) *node {
-	commonBitsLen := commonPrefixLen(oldNode.bits, routeBits)
-	commonBits := oldNode.bits[:commonBitsLen]
+	commonBitsLen := oldNode.bits.CommonPrefixLen(routeBits)
+	commonBits := oldNode.bits.Prefix(commonBitsLen)
 
 	routeNode := &node{
-		bits:     routeBits[commonBitsLen:],
+		bits:     routeBits.Suffix(commonBitsLen),
 		children: nil,
 	}
-	oldNode.bits = oldNode.bits[commonBitsLen:]
+	oldNode.bits = oldNode.bits.Suffix(commonBitsLen)
 
 	newNode := &node{
 		bits:     commonBits,
 		children: &[2]*node{},
 	}
-	newNode.children[routeNode.bits[0]] = routeNode
-	newNode.children[oldNode.bits[0]] = oldNode
+	newNode.children[routeNode.bits.Bit(0)] = routeNode
+	newNode.children[oldNode.bits.Bit(0)] = oldNode
 
 	return newNode
 }
@@ -143,7 +130,7 @@ func (n *node) maybeRemoveRedundantChildren() bool {
 		return false
 	}
 
-	if len(n.children[0].bits) != 1 || len(n.children[1].bits) != 1 {
+	if n.children[0].bits.Len() != 1 || n.children[1].bits.Len() != 1 {
 		return false
 	}
 
@@ -151,6 +138,116 @@ func (n *node) maybeRemoveRedundantChildren() bool {
 	return true
 }
 
+// RemoveRoute removes a BitSlice from the trie. Any stored routes covered by the removed route are deleted outright,
+// and a stored route covering the removed route is split into the smallest set of routes covering what remains.
+// Removing a route that isn't in the trie has no effect.
+func (t *RSTrie) RemoveRoute(routeBits bitslice.BitSlice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.root == nil {
+		return
+	}
+
+	t.root = t.root.removeRoute(routeBits)
+}
+
+// removeRoute returns the node that should take the current node's place once the requested route has been removed
+// from it, or nil if nothing remains.
+func (n *node) removeRoute(remainingRouteBits bitslice.BitSlice) *node {
+	remainingRouteBitsLen := remainingRouteBits.Len()
+	curNodeBitsLen := n.bits.Len()
+
+	// Does the requested route cover the current node? If so, the whole node goes.
+	if remainingRouteBitsLen <= curNodeBitsLen && n.bits.HasPrefix(remainingRouteBits) {
+		return nil
+	}
+
+	// Does the requested route diverge from the current node? If so, there's nothing to remove.
+	if !remainingRouteBits.HasPrefix(n.bits) {
+		return n
+	}
+
+	// The current node covers the requested route. If it's a leaf, carve the requested route out of it.
+	if n.isLeaf() {
+		return carveLeaf(n.bits, remainingRouteBits.Suffix(curNodeBitsLen))
+	}
+
+	// Otherwise, we traverse to the correct child.
+	whichChild := remainingRouteBits.Bit(curNodeBitsLen)
+	n.children[whichChild] = n.children[whichChild].removeRoute(remainingRouteBits.Suffix(curNodeBitsLen))
+	if n.children[whichChild] != nil {
+		return n
+	}
+
+	// With one child gone, the current node and its remaining child are folded together.
+	sibling := n.children[1-whichChild]
+	return &node{
+		bits:     n.bits.Append(sibling.bits),
+		children: sibling.children,
+	}
+}
+
+// carveLeaf builds the subtrie that remains when holeBits are removed from a leaf with bits leafBits. Each bit of
+// holeBits contributes one leaf: the sibling of the path to the hole at that depth.
+func carveLeaf(leafBits, holeBits bitslice.BitSlice) *node {
+	sibling := &node{
+		bits:     bitslice.New(1 - holeBits.Bit(0)),
+		children: nil,
+	}
+
+	if holeBits.Len() == 1 {
+		return &node{
+			bits:     leafBits.Append(sibling.bits),
+			children: nil,
+		}
+	}
+
+	n := &node{
+		bits:     leafBits,
+		children: &[2]*node{},
+	}
+	n.children[holeBits.Bit(0)] = carveLeaf(holeBits.Prefix(1), holeBits.Suffix(1))
+	n.children[sibling.bits.Bit(0)] = sibling
+
+	return n
+}
+
+// Lookup returns the stored route covering routeBits, if there is one. As routes in the trie never overlap, there is
+// at most one such route.
+func (t *RSTrie) Lookup(routeBits bitslice.BitSlice) (bitslice.BitSlice, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	matchedBitsLen := 0
+	n := t.root
+	for n != nil {
+		if !routeBits.Suffix(matchedBitsLen).HasPrefix(n.bits) {
+			return bitslice.BitSlice{}, false
+		}
+		matchedBitsLen += n.bits.Len()
+
+		if n.isLeaf() {
+			return routeBits.Prefix(matchedBitsLen), true
+		}
+
+		// An internal node never represents a complete subtrie, so a route ending here isn't covered.
+		if matchedBitsLen == routeBits.Len() {
+			return bitslice.BitSlice{}, false
+		}
+
+		n = n.children[routeBits.Bit(matchedBitsLen)]
+	}
+
+	return bitslice.BitSlice{}, false
+}
+
+// Contains reports whether routeBits is covered by a route stored in the trie.
+func (t *RSTrie) Contains(routeBits bitslice.BitSlice) bool {
+	_, ok := t.Lookup(routeBits)
+	return ok
+}
+
 type traversalStep struct {
 	n                  *node
 	precedingRouteBits bitslice.BitSlice
@@ -166,8 +263,20 @@ func (t *RSTrie) Contents() []bitslice.BitSlice {
 	return slices.Collect(t.Each())
 }
 
-// Each returns Navigate the trie and return each complete bitslice.
+// Each returns an iterator over each stored route, in ascending order. As stored routes never overlap, this is both
+// ascending order of the addresses they start at and of the addresses they end at.
 func (t *RSTrie) Each() iter.Seq[bitslice.BitSlice] {
+	return t.each(0)
+}
+
+// EachDescending returns an iterator over each stored route, in descending order.
+func (t *RSTrie) EachDescending() iter.Seq[bitslice.BitSlice] {
+	return t.each(1)
+}
+
+// each navigates the trie depth-first, visiting the child for firstBit before its sibling, and yields each complete
+// bitslice.
+func (t *RSTrie) each(firstBit byte) iter.Seq[bitslice.BitSlice] {
 	return func(yield func(bitslice.BitSlice) bool) {
 		t.mu.RLock()
 		defer t.mu.RUnlock()
@@ -185,9 +294,7 @@ func (t *RSTrie) Each() iter.Seq[bitslice.BitSlice] {
 		for remainingSteps.Len() > 0 {
 			step := remainingSteps.Remove(remainingSteps.Front()).(traversalStep)
 
-			stepRouteBits := bitslice.BitSlice{}
-			stepRouteBits = append(stepRouteBits, step.precedingRouteBits...)
-			stepRouteBits = append(stepRouteBits, step.n.bits...)
+			stepRouteBits := step.precedingRouteBits.Append(step.n.bits)
 
 			if step.n.isLeaf() {
 				if !yield(stepRouteBits) {
@@ -195,11 +302,11 @@ func (t *RSTrie) Each() iter.Seq[bitslice.BitSlice] {
 				}
 			} else {
 				remainingSteps.PushFront(traversalStep{
-					n:                  step.n.children[1],
+					n:                  step.n.children[1-firstBit],
 					precedingRouteBits: stepRouteBits,
 				})
 				remainingSteps.PushFront(traversalStep{
-					n:                  step.n.children[0],
+					n:                  step.n.children[firstBit],
 					precedingRouteBits: stepRouteBits,
 				})
 			}
@@ -1,49 +1,16 @@
 package rstrie
 
 import (
+	"runtime"
+	"slices"
 	"sync"
 	"testing"
 
 	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/PatrickCronin/routesum/pkg/routesum/internal/corpus"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCommonPrefixLen(t *testing.T) {
-	tests := []struct {
-		name     string
-		a, b     bitslice.BitSlice
-		expected int
-	}{
-		{
-			name:     "differing first bit",
-			a:        bitslice.BitSlice{0},
-			b:        bitslice.BitSlice{1},
-			expected: 0,
-		},
-		{
-			name:     "differing second bit",
-			a:        bitslice.BitSlice{0, 0},
-			b:        bitslice.BitSlice{0, 1},
-			expected: 1,
-		},
-		{
-			name:     "nothing different",
-			a:        bitslice.BitSlice{0, 0, 0, 1},
-			b:        bitslice.BitSlice{0, 0, 0, 1},
-			expected: 4,
-		},
-	}
-
-	for _, test := range tests {
-		assert.Equal(
-			t,
-			test.expected,
-			commonPrefixLen(test.a, test.b),
-			test.name,
-		)
-	}
-}
-
 func TestRSTrieInsertRoute(t *testing.T) { //nolint: funlen
 	tests := []struct {
 		name     string
@@ -52,68 +19,68 @@ func TestRSTrieInsertRoute(t *testing.T) { //nolint: funlen
 	}{
 		{
 			name:   "add one child",
-			routes: []bitslice.BitSlice{{0}},
+			routes: []bitslice.BitSlice{bitslice.New(0)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{0},
+					bits:     bitslice.New(0),
 					children: nil,
 				},
 			},
 		},
 		{
 			name:   "add two children, completing the root node's subtrie",
-			routes: []bitslice.BitSlice{{0}, {1}},
+			routes: []bitslice.BitSlice{bitslice.New(0), bitslice.New(1)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{},
+					bits:     bitslice.New(),
 					children: nil,
 				},
 			},
 		},
 		{
 			name:   "split root, root is empty",
-			routes: []bitslice.BitSlice{{0, 0}, {1, 1}},
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits: bitslice.BitSlice{},
+					bits: bitslice.New(),
 					children: &[2]*node{
-						0: {bits: bitslice.BitSlice{0, 0}},
-						1: {bits: bitslice.BitSlice{1, 1}},
+						0: {bits: bitslice.New(0, 0)},
+						1: {bits: bitslice.New(1, 1)},
 					},
 				},
 			},
 		},
 		{
 			name:   "split root, root is not empty",
-			routes: []bitslice.BitSlice{{0, 0}, {0, 1, 0}},
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 0)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits: bitslice.BitSlice{0},
+					bits: bitslice.New(0),
 					children: &[2]*node{
-						0: {bits: bitslice.BitSlice{0}},
-						1: {bits: bitslice.BitSlice{1, 0}},
+						0: {bits: bitslice.New(0)},
+						1: {bits: bitslice.New(1, 0)},
 					},
 				},
 			},
 		},
 		{
 			name:   "split root, traverse, and split internal",
-			routes: []bitslice.BitSlice{{0}, {1, 0, 0}, {1, 1, 0}},
+			routes: []bitslice.BitSlice{bitslice.New(0), bitslice.New(1, 0, 0), bitslice.New(1, 1, 0)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits: bitslice.BitSlice{},
+					bits: bitslice.New(),
 					children: &[2]*node{
-						0: {bits: bitslice.BitSlice{0}},
+						0: {bits: bitslice.New(0)},
 						1: {
-							bits: bitslice.BitSlice{1},
+							bits: bitslice.New(1),
 							children: &[2]*node{
-								0: {bits: bitslice.BitSlice{0, 0}},
-								1: {bits: bitslice.BitSlice{1, 0}},
+								0: {bits: bitslice.New(0, 0)},
+								1: {bits: bitslice.New(1, 0)},
 							},
 						},
 					},
@@ -122,22 +89,22 @@ func TestRSTrieInsertRoute(t *testing.T) { //nolint: funlen
 		},
 		{
 			name:   "covered routes are ignored",
-			routes: []bitslice.BitSlice{{0}, {0, 0}},
+			routes: []bitslice.BitSlice{bitslice.New(0), bitslice.New(0, 0)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{0},
+					bits:     bitslice.New(0),
 					children: nil,
 				},
 			},
 		},
 		{
 			name:   "route covering node replaces it",
-			routes: []bitslice.BitSlice{{0, 0}, {0}},
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0)},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{0},
+					bits:     bitslice.New(0),
 					children: nil,
 				},
 			},
@@ -145,15 +112,15 @@ func TestRSTrieInsertRoute(t *testing.T) { //nolint: funlen
 		{
 			name: "completed subtries are simpliflied",
 			routes: []bitslice.BitSlice{
-				{1},
-				{0, 1},
-				{0, 0, 1},
-				{0, 0, 0},
+				bitslice.New(1),
+				bitslice.New(0, 1),
+				bitslice.New(0, 0, 1),
+				bitslice.New(0, 0, 0),
 			},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{},
+					bits:     bitslice.New(),
 					children: nil,
 				},
 			},
@@ -161,14 +128,14 @@ func TestRSTrieInsertRoute(t *testing.T) { //nolint: funlen
 		{
 			name: "completed subtries are simplified when new route covers current",
 			routes: []bitslice.BitSlice{
-				{0, 0},
-				{0, 1, 1},
-				{0, 1},
+				bitslice.New(0, 0),
+				bitslice.New(0, 1, 1),
+				bitslice.New(0, 1),
 			},
 			expected: &RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{0},
+					bits:     bitslice.New(0),
 					children: nil,
 				},
 			},
@@ -199,11 +166,11 @@ func TestRSTrieContents(t *testing.T) { //nolint: funlen
 			trie: RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     nil,
+					bits:     bitslice.New(),
 					children: nil,
 				},
 			},
-			expected: []bitslice.BitSlice{{}},
+			expected: []bitslice.BitSlice{bitslice.New()},
 		},
 		{
 			name: "empty trie",
@@ -218,36 +185,36 @@ func TestRSTrieContents(t *testing.T) { //nolint: funlen
 			trie: RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{0},
+					bits:     bitslice.New(0),
 					children: nil,
 				},
 			},
-			expected: []bitslice.BitSlice{{0}},
+			expected: []bitslice.BitSlice{bitslice.New(0)},
 		},
 		{
 			name: "single one-child trie",
 			trie: RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits:     bitslice.BitSlice{1},
+					bits:     bitslice.New(1),
 					children: nil,
 				},
 			},
-			expected: []bitslice.BitSlice{{1}},
+			expected: []bitslice.BitSlice{bitslice.New(1)},
 		},
 		{
 			name: "two-level trie",
 			trie: RSTrie{
 				mu: sync.RWMutex{},
 				root: &node{
-					bits: bitslice.BitSlice{0, 0},
+					bits: bitslice.New(0, 0),
 					children: &[2]*node{
-						0: {bits: bitslice.BitSlice{0}},
-						1: {bits: bitslice.BitSlice{1, 0}},
+						0: {bits: bitslice.New(0)},
+						1: {bits: bitslice.New(1, 0)},
 					},
 				},
 			},
-			expected: []bitslice.BitSlice{{0, 0, 0}, {0, 0, 1, 0}},
+			expected: []bitslice.BitSlice{bitslice.New(0, 0, 0), bitslice.New(0, 0, 1, 0)},
 		},
 	}
 
@@ -257,3 +224,316 @@ func TestRSTrieContents(t *testing.T) { //nolint: funlen
 		})
 	}
 }
+
+func TestRSTrieRemoveRoute(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name     string
+		routes   []bitslice.BitSlice
+		remove   []bitslice.BitSlice
+		reinsert []bitslice.BitSlice
+		expected *RSTrie
+	}{
+		{
+			name:   "remove from an empty trie",
+			routes: []bitslice.BitSlice{},
+			remove: []bitslice.BitSlice{bitslice.New(0)},
+			expected: &RSTrie{
+				mu:   sync.RWMutex{},
+				root: nil,
+			},
+		},
+		{
+			name:   "remove the only route",
+			routes: []bitslice.BitSlice{bitslice.New(0, 1)},
+			remove: []bitslice.BitSlice{bitslice.New(0, 1)},
+			expected: &RSTrie{
+				mu:   sync.RWMutex{},
+				root: nil,
+			},
+		},
+		{
+			name:   "remove a route covering everything",
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
+			remove: []bitslice.BitSlice{bitslice.New()},
+			expected: &RSTrie{
+				mu:   sync.RWMutex{},
+				root: nil,
+			},
+		},
+		{
+			name:   "remove an absent route",
+			routes: []bitslice.BitSlice{bitslice.New(0, 1)},
+			remove: []bitslice.BitSlice{bitslice.New(1)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits:     bitslice.New(0, 1),
+					children: nil,
+				},
+			},
+		},
+		{
+			name:   "removing a child folds its sibling into the parent",
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 0)},
+			remove: []bitslice.BitSlice{bitslice.New(0, 0)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits:     bitslice.New(0, 1, 0),
+					children: nil,
+				},
+			},
+		},
+		{
+			name:   "removing a child keeps the sibling's children",
+			routes: []bitslice.BitSlice{bitslice.New(0), bitslice.New(1, 0, 0), bitslice.New(1, 1, 0)},
+			remove: []bitslice.BitSlice{bitslice.New(0)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits: bitslice.New(1),
+					children: &[2]*node{
+						0: {bits: bitslice.New(0, 0)},
+						1: {bits: bitslice.New(1, 0)},
+					},
+				},
+			},
+		},
+		{
+			name:   "carve one bit out of a leaf",
+			routes: []bitslice.BitSlice{bitslice.New(0)},
+			remove: []bitslice.BitSlice{bitslice.New(0, 1)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits:     bitslice.New(0, 0),
+					children: nil,
+				},
+			},
+		},
+		{
+			name:   "carve several bits out of a leaf",
+			routes: []bitslice.BitSlice{bitslice.New()},
+			remove: []bitslice.BitSlice{bitslice.New(1, 0, 1)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits: bitslice.New(),
+					children: &[2]*node{
+						0: {bits: bitslice.New(0)},
+						1: {
+							bits: bitslice.New(1),
+							children: &[2]*node{
+								0: {bits: bitslice.New(0, 0)},
+								1: {bits: bitslice.New(1)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "carve out of a leaf below an internal node",
+			routes: []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
+			remove: []bitslice.BitSlice{bitslice.New(1, 1, 0)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits: bitslice.New(),
+					children: &[2]*node{
+						0: {bits: bitslice.New(0, 0)},
+						1: {bits: bitslice.New(1, 1, 1)},
+					},
+				},
+			},
+		},
+		{
+			name:     "carving and re-inserting restores the leaf",
+			routes:   []bitslice.BitSlice{bitslice.New(0)},
+			remove:   []bitslice.BitSlice{bitslice.New(0, 1, 1)},
+			reinsert: []bitslice.BitSlice{bitslice.New(0, 1, 1)},
+			expected: &RSTrie{
+				mu: sync.RWMutex{},
+				root: &node{
+					bits:     bitslice.New(0),
+					children: nil,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trie := NewRSTrie()
+
+			for _, route := range test.routes {
+				trie.InsertRoute(route)
+			}
+			for _, route := range test.remove {
+				trie.RemoveRoute(route)
+			}
+			for _, route := range test.reinsert {
+				trie.InsertRoute(route)
+			}
+
+			assert.Equal(t, test.expected, trie, "got expected rstrie")
+		})
+	}
+}
+
+func TestRSTrieLookup(t *testing.T) { //nolint: funlen
+	trie := NewRSTrie()
+	for _, route := range []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 0, 1), bitslice.New(1, 1, 0, 0)} {
+		trie.InsertRoute(route)
+	}
+
+	tests := []struct {
+		name          string
+		route         bitslice.BitSlice
+		expected      bitslice.BitSlice
+		expectedFound bool
+	}{
+		{
+			name:          "exact match",
+			route:         bitslice.New(1, 0, 1),
+			expected:      bitslice.New(1, 0, 1),
+			expectedFound: true,
+		},
+		{
+			name:          "covered route",
+			route:         bitslice.New(0, 0, 1, 1),
+			expected:      bitslice.New(0, 0),
+			expectedFound: true,
+		},
+		{
+			name:          "route diverging within a node",
+			route:         bitslice.New(1, 0, 0),
+			expected:      bitslice.New(),
+			expectedFound: false,
+		},
+		{
+			name:          "route diverging at a branch",
+			route:         bitslice.New(0, 1),
+			expected:      bitslice.New(),
+			expectedFound: false,
+		},
+		{
+			name:          "route ending at an internal node",
+			route:         bitslice.New(1),
+			expected:      bitslice.New(),
+			expectedFound: false,
+		},
+		{
+			name:          "route covering a stored route",
+			route:         bitslice.New(1, 1),
+			expected:      bitslice.New(),
+			expectedFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			covering, found := trie.Lookup(test.route)
+			assert.Equal(t, test.expectedFound, found, "found as expected")
+			assert.Equal(t, test.expected, covering, "got expected covering route")
+			assert.Equal(t, test.expectedFound, trie.Contains(test.route), "contained as expected")
+		})
+	}
+
+	_, found := NewRSTrie().Lookup(bitslice.New(0))
+	assert.False(t, found, "nothing is found in an empty trie")
+
+	complete := NewRSTrie()
+	complete.InsertRoute(bitslice.New())
+	covering, found := complete.Lookup(bitslice.New(1, 0))
+	assert.True(t, found, "everything is found in a complete trie")
+	assert.Equal(t, bitslice.New(), covering, "the complete route covers everything")
+}
+
+func TestRSTrieEachOrder(t *testing.T) {
+	trie := NewRSTrie()
+	for _, route := range []bitslice.BitSlice{
+		bitslice.New(1, 1),
+		bitslice.New(0, 0, 1),
+		bitslice.New(1, 0, 0, 1),
+		bitslice.New(0, 1),
+		bitslice.New(0, 0, 0, 0),
+	} {
+		trie.InsertRoute(route)
+	}
+
+	ascending := []bitslice.BitSlice{
+		bitslice.New(0, 0, 0, 0),
+		bitslice.New(0, 0, 1),
+		bitslice.New(0, 1),
+		bitslice.New(1, 0, 0, 1),
+		bitslice.New(1, 1),
+	}
+	assert.Equal(t, ascending, slices.Collect(trie.Each()), "Each yields ascending routes")
+
+	slices.Reverse(ascending)
+	assert.Equal(t, ascending, slices.Collect(trie.EachDescending()), "EachDescending yields descending routes")
+
+	assert.Empty(t, slices.Collect(NewRSTrie().EachDescending()), "an empty trie yields nothing")
+}
+
+// BenchmarkRSTrieInsertRoute inserts each corpus workload into a trie.
+func BenchmarkRSTrieInsertRoute(b *testing.B) {
+	for _, workload := range corpus.Workloads() {
+		for _, family := range corpus.Families() {
+			routes := corpusRoutes(b, workload, family)
+
+			b.Run(workload.Name+"/"+family.Name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				for range b.N {
+					trie := NewRSTrie()
+					for _, route := range routes {
+						trie.InsertRoute(route)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkRSTrieEach iterates over a trie holding each corpus workload.
+func BenchmarkRSTrieEach(b *testing.B) {
+	for _, workload := range corpus.Workloads() {
+		for _, family := range corpus.Families() {
+			trie := NewRSTrie()
+			for _, route := range corpusRoutes(b, workload, family) {
+				trie.InsertRoute(route)
+			}
+
+			b.Run(workload.Name+"/"+family.Name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				for range b.N {
+					count := 0
+					for range trie.Each() {
+						count++
+					}
+					runtime.KeepAlive(count)
+				}
+			})
+		}
+	}
+}
+
+// corpusRoutes returns a corpus workload as routes.
+func corpusRoutes(b *testing.B, workload corpus.Workload, family corpus.Family) []bitslice.BitSlice {
+	b.Helper()
+
+	prefixes := workload.Generate(family, 100_000)
+	routes := make([]bitslice.BitSlice, 0, len(prefixes))
+	for _, p := range prefixes {
+		bits, err := bitslice.NewFromBytes(p.Addr().AsSlice())
+		if err != nil {
+			b.Fatal(err)
+		}
+		routes = append(routes, bits.Prefix(p.Bits()))
+	}
+
+	return routes
+}
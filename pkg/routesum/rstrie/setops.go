@@ -0,0 +1,222 @@
+package rstrie
+
+import (
+	"sync"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+)
+
+// setOp identifies a set operation between two tries.
+type setOp int
+
+const (
+	opUnion setOp = iota
+	opIntersect
+	opDifference
+	opSymmetricDifference
+)
+
+// Union returns a new RSTrie covering everything covered by either t or other.
+func (t *RSTrie) Union(other *RSTrie) *RSTrie {
+	return t.combine(other, opUnion)
+}
+
+// Intersect returns a new RSTrie covering everything covered by both t and other.
+func (t *RSTrie) Intersect(other *RSTrie) *RSTrie {
+	return t.combine(other, opIntersect)
+}
+
+// Difference returns a new RSTrie covering everything covered by t but not by other.
+func (t *RSTrie) Difference(other *RSTrie) *RSTrie {
+	return t.combine(other, opDifference)
+}
+
+// SymmetricDifference returns a new RSTrie covering everything covered by exactly one of t and other.
+func (t *RSTrie) SymmetricDifference(other *RSTrie) *RSTrie {
+	return t.combine(other, opSymmetricDifference)
+}
+
+// Complement returns a new RSTrie covering everything not covered by t.
+func (t *RSTrie) Complement() *RSTrie {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return &RSTrie{
+		mu:   sync.RWMutex{},
+		root: combineNodes(newCompleteNode(), t.root, opDifference),
+	}
+}
+
+// combine walks t and other in lockstep, building a new trie from the result of op. Neither input is modified, and
+// the result shares no nodes with either.
+func (t *RSTrie) combine(other *RSTrie, op setOp) *RSTrie {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if other != t {
+		other.mu.RLock()
+		defer other.mu.RUnlock()
+	}
+
+	return &RSTrie{
+		mu:   sync.RWMutex{},
+		root: combineNodes(t.root, other.root, op),
+	}
+}
+
+// combineNodes returns the result of op on two subtries rooted at the same position. A nil node represents an empty
+// subtrie, and a leaf with no bits a complete one.
+func combineNodes(a, b *node, op setOp) *node {
+	if result, ok := combineTrivially(a, b, op); ok {
+		return result
+	}
+
+	// When neither subtrie is complete, any bits they share lead to the same result, so they can be skipped over
+	// together.
+	if !a.isComplete() && !b.isComplete() {
+		if commonBitsLen := a.bits.CommonPrefixLen(b.bits); commonBitsLen > 0 {
+			return prependBits(
+				a.bits.Prefix(commonBitsLen),
+				combineNodes(a.descend(commonBitsLen), b.descend(commonBitsLen), op),
+			)
+		}
+	}
+
+	// Otherwise, combine each half separately.
+	return joinHalves(
+		combineNodes(a.half(0), b.half(0), op),
+		combineNodes(a.half(1), b.half(1), op),
+	)
+}
+
+// combineTrivially returns the result of op when it can be determined without looking any deeper into either subtrie.
+func combineTrivially(a, b *node, op setOp) (*node, bool) {
+	switch op {
+	case opUnion:
+		switch {
+		case a == nil:
+			return b.clone(), true
+		case b == nil:
+			return a.clone(), true
+		case a.isComplete() || b.isComplete():
+			return newCompleteNode(), true
+		}
+	case opIntersect:
+		switch {
+		case a == nil || b == nil:
+			return nil, true
+		case a.isComplete():
+			return b.clone(), true
+		case b.isComplete():
+			return a.clone(), true
+		}
+	case opDifference:
+		switch {
+		case a == nil || b.isComplete():
+			return nil, true
+		case b == nil:
+			return a.clone(), true
+		}
+	case opSymmetricDifference:
+		switch {
+		case a == nil:
+			return b.clone(), true
+		case b == nil:
+			return a.clone(), true
+		case a.isComplete() && b.isComplete():
+			return nil, true
+		}
+	}
+
+	return nil, false
+}
+
+func newCompleteNode() *node {
+	return &node{
+		bits:     bitslice.BitSlice{},
+		children: nil,
+	}
+}
+
+// isComplete reports whether the node represents a complete subtrie from its own position.
+func (n *node) isComplete() bool {
+	return n != nil && n.isLeaf() && n.bits.Len() == 0
+}
+
+// descend returns a view of the node as seen from numBits further down its own bits. The view shares its children
+// with the node, so it must not be modified.
+func (n *node) descend(numBits int) *node {
+	return &node{
+		bits:     n.bits.Suffix(numBits),
+		children: n.children,
+	}
+}
+
+// half returns a view of the subtrie found by following one bit from the node's position, or nil if that subtrie is
+// empty. Like descend, the view must not be modified.
+func (n *node) half(bit byte) *node {
+	switch {
+	case n == nil:
+		return nil
+	case n.isComplete():
+		return newCompleteNode()
+	case n.bits.Len() > 0:
+		if n.bits.Bit(0) != bit {
+			return nil
+		}
+		return n.descend(1)
+	default:
+		return n.children[bit].descend(1)
+	}
+}
+
+// joinHalves builds the subtrie whose halves are the given subtries, each rooted one bit below it.
+func joinHalves(zero, one *node) *node {
+	switch {
+	case zero == nil && one == nil:
+		return nil
+	case zero.isComplete() && one.isComplete():
+		return newCompleteNode()
+	case zero == nil:
+		return prependBits(bitslice.New(1), one)
+	case one == nil:
+		return prependBits(bitslice.New(0), zero)
+	default:
+		return &node{
+			bits: bitslice.BitSlice{},
+			children: &[2]*node{
+				prependBits(bitslice.New(0), zero),
+				prependBits(bitslice.New(1), one),
+			},
+		}
+	}
+}
+
+// prependBits returns the node moved up the trie by the given bits.
+func prependBits(bits bitslice.BitSlice, n *node) *node {
+	if n == nil {
+		return nil
+	}
+
+	return &node{
+		bits:     bits.Append(n.bits),
+		children: n.children,
+	}
+}
+
+// clone returns a deep copy of the subtrie rooted at the node.
+func (n *node) clone() *node {
+	if n == nil {
+		return nil
+	}
+
+	c := &node{
+		bits:     n.bits,
+		children: nil,
+	}
+	if !n.isLeaf() {
+		c.children = &[2]*node{n.children[0].clone(), n.children[1].clone()}
+	}
+
+	return c
+}
@@ -0,0 +1,215 @@
+package rstrie
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/bitslice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRSTrieSetOps(t *testing.T) { //nolint: funlen
+	tests := []struct {
+		name               string
+		a, b               []bitslice.BitSlice
+		union              []bitslice.BitSlice
+		intersect          []bitslice.BitSlice
+		difference         []bitslice.BitSlice
+		symmetricDiff      []bitslice.BitSlice
+		differenceOtherWay []bitslice.BitSlice
+	}{
+		{
+			name:               "empty tries",
+			a:                  nil,
+			b:                  nil,
+			union:              nil,
+			intersect:          nil,
+			difference:         nil,
+			symmetricDiff:      nil,
+			differenceOtherWay: nil,
+		},
+		{
+			name:               "one empty trie",
+			a:                  []bitslice.BitSlice{bitslice.New(0, 1)},
+			b:                  nil,
+			union:              []bitslice.BitSlice{bitslice.New(0, 1)},
+			intersect:          nil,
+			difference:         []bitslice.BitSlice{bitslice.New(0, 1)},
+			symmetricDiff:      []bitslice.BitSlice{bitslice.New(0, 1)},
+			differenceOtherWay: nil,
+		},
+		{
+			name:               "disjoint siblings summarize",
+			a:                  []bitslice.BitSlice{bitslice.New(0, 1, 0)},
+			b:                  []bitslice.BitSlice{bitslice.New(0, 1, 1)},
+			union:              []bitslice.BitSlice{bitslice.New(0, 1)},
+			intersect:          nil,
+			difference:         []bitslice.BitSlice{bitslice.New(0, 1, 0)},
+			symmetricDiff:      []bitslice.BitSlice{bitslice.New(0, 1)},
+			differenceOtherWay: []bitslice.BitSlice{bitslice.New(0, 1, 1)},
+		},
+		{
+			name:               "covering route",
+			a:                  []bitslice.BitSlice{bitslice.New(1)},
+			b:                  []bitslice.BitSlice{bitslice.New(1, 0, 1)},
+			union:              []bitslice.BitSlice{bitslice.New(1)},
+			intersect:          []bitslice.BitSlice{bitslice.New(1, 0, 1)},
+			difference:         []bitslice.BitSlice{bitslice.New(1, 0, 0), bitslice.New(1, 1)},
+			symmetricDiff:      []bitslice.BitSlice{bitslice.New(1, 0, 0), bitslice.New(1, 1)},
+			differenceOtherWay: nil,
+		},
+		{
+			name:               "complete trie",
+			a:                  []bitslice.BitSlice{bitslice.New()},
+			b:                  []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
+			union:              []bitslice.BitSlice{bitslice.New()},
+			intersect:          []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
+			difference:         []bitslice.BitSlice{bitslice.New(0, 1), bitslice.New(1, 0)},
+			symmetricDiff:      []bitslice.BitSlice{bitslice.New(0, 1), bitslice.New(1, 0)},
+			differenceOtherWay: nil,
+		},
+		{
+			name:               "partial overlap",
+			a:                  []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 0)},
+			b:                  []bitslice.BitSlice{bitslice.New(0, 1), bitslice.New(1, 1, 1)},
+			union:              []bitslice.BitSlice{bitslice.New(0), bitslice.New(1, 1, 1)},
+			intersect:          []bitslice.BitSlice{bitslice.New(0, 1, 0)},
+			difference:         []bitslice.BitSlice{bitslice.New(0, 0)},
+			symmetricDiff:      []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(0, 1, 1), bitslice.New(1, 1, 1)},
+			differenceOtherWay: []bitslice.BitSlice{bitslice.New(0, 1, 1), bitslice.New(1, 1, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newRSTrieFromRoutes(test.a)
+			b := newRSTrieFromRoutes(test.b)
+
+			assert.Equal(t, test.union, a.Union(b).Contents(), "got expected union")
+			assert.Equal(t, test.intersect, a.Intersect(b).Contents(), "got expected intersection")
+			assert.Equal(t, test.difference, a.Difference(b).Contents(), "got expected difference")
+			assert.Equal(t, test.symmetricDiff, a.SymmetricDifference(b).Contents(), "got expected symmetric diff")
+			assert.Equal(t, test.differenceOtherWay, b.Difference(a).Contents(), "got expected reversed difference")
+
+			assert.Equal(t, test.a, nilIfEmpty(a.Contents()), "first operand is unchanged")
+			assert.Equal(t, test.b, nilIfEmpty(b.Contents()), "second operand is unchanged")
+		})
+	}
+}
+
+func TestRSTrieSetOpsRandomized(t *testing.T) {
+	const width = 6
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint: gosec
+
+	ops := []struct {
+		name     string
+		trieOp   func(a, b *RSTrie) *RSTrie
+		memberOp func(inA, inB bool) bool
+	}{
+		{"union", (*RSTrie).Union, func(inA, inB bool) bool { return inA || inB }},
+		{"intersect", (*RSTrie).Intersect, func(inA, inB bool) bool { return inA && inB }},
+		{"difference", (*RSTrie).Difference, func(inA, inB bool) bool { return inA && !inB }},
+		{"symmetric difference", (*RSTrie).SymmetricDifference, func(inA, inB bool) bool { return inA != inB }},
+	}
+
+	for range 200 {
+		a := newRSTrieFromRoutes(randomRoutes(rng, width))
+		b := newRSTrieFromRoutes(randomRoutes(rng, width))
+
+		for _, op := range ops {
+			result := op.trieOp(a, b)
+
+			for _, addr := range allRoutes(width) {
+				assert.Equal(
+					t,
+					op.memberOp(a.Contains(addr), b.Contains(addr)),
+					result.Contains(addr),
+					"%s: membership of %v", op.name, addr,
+				)
+			}
+
+			canonical := newRSTrieFromRoutes(result.Contents())
+			assert.Equal(t, canonical.root, result.root, "%s: result is in its most summarized form", op.name)
+		}
+	}
+}
+
+func newRSTrieFromRoutes(routes []bitslice.BitSlice) *RSTrie {
+	trie := NewRSTrie()
+	for _, route := range routes {
+		trie.InsertRoute(route)
+	}
+
+	return trie
+}
+
+func nilIfEmpty(routes []bitslice.BitSlice) []bitslice.BitSlice {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	return routes
+}
+
+func randomRoutes(rng *rand.Rand, width int) []bitslice.BitSlice {
+	routes := make([]bitslice.BitSlice, rng.IntN(6))
+	for i := range routes {
+		for range rng.IntN(width + 1) {
+			routes[i] = routes[i].AppendBit(byte(rng.IntN(2)))
+		}
+	}
+
+	return routes
+}
+
+// allRoutes returns every full-width route.
+func allRoutes(width int) []bitslice.BitSlice {
+	routes := []bitslice.BitSlice{bitslice.New()}
+	for range width {
+		var longer []bitslice.BitSlice
+		for _, route := range routes {
+			longer = append(longer, route.AppendBit(0), route.AppendBit(1))
+		}
+		routes = longer
+	}
+
+	return routes
+}
+
+func TestRSTrieComplement(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   []bitslice.BitSlice
+		expected []bitslice.BitSlice
+	}{
+		{
+			name:     "empty trie",
+			routes:   nil,
+			expected: []bitslice.BitSlice{bitslice.New()},
+		},
+		{
+			name:     "complete trie",
+			routes:   []bitslice.BitSlice{bitslice.New()},
+			expected: nil,
+		},
+		{
+			name:     "single route",
+			routes:   []bitslice.BitSlice{bitslice.New(1, 0, 1)},
+			expected: []bitslice.BitSlice{bitslice.New(0), bitslice.New(1, 0, 0), bitslice.New(1, 1)},
+		},
+		{
+			name:     "several routes",
+			routes:   []bitslice.BitSlice{bitslice.New(0, 0), bitslice.New(1, 1)},
+			expected: []bitslice.BitSlice{bitslice.New(0, 1), bitslice.New(1, 0)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trie := newRSTrieFromRoutes(test.routes)
+			complement := trie.Complement()
+			assert.Equal(t, test.expected, complement.Contents(), "got expected complement")
+			assert.Equal(t, test.routes, nilIfEmpty(complement.Complement().Contents()), "complement is reversible")
+		})
+	}
+}
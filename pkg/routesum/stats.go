@@ -0,0 +1,62 @@
+package routesum
+
+import (
+	"math/big"
+
+	"github.com/PatrickCronin/routesum/pkg/routesum/rstrie"
+)
+
+// FamilyStats describes the part of a summary in one address family.
+type FamilyStats struct {
+	// Entries is the number of networks and IPs in the summary.
+	Entries int `json:"entries"`
+
+	// Addresses is the number of addresses they cover.
+	Addresses *big.Int `json:"addresses"`
+
+	// PrefixLengths counts the entries of each prefix length. IPs count as networks of the family's full bit length.
+	PrefixLengths map[int]int `json:"prefixLengths"`
+}
+
+// Stats describes a summary.
+type Stats struct {
+	// Inputs is the number of networks and IPs inserted, including any already covered. A RouteSum made by a set
+	// operation such as Union starts with none.
+	Inputs int64 `json:"inputs"`
+
+	// Entries is the number of networks and IPs in the summary, across both address families.
+	Entries int `json:"entries"`
+
+	IPv4 FamilyStats `json:"ipv4"`
+	IPv6 FamilyStats `json:"ipv6"`
+}
+
+// Stats counts the entries in the summary and the addresses they cover, and how many networks and IPs were inserted
+// to make it.
+func (rs *RouteSum) Stats() Stats {
+	ipv4 := familyStats(rs.ipv4, 8*4)
+	ipv6 := familyStats(rs.ipv6, 8*16)
+
+	return Stats{
+		Inputs:  rs.inputs.Load(),
+		Entries: ipv4.Entries + ipv6.Entries,
+		IPv4:    ipv4,
+		IPv6:    ipv6,
+	}
+}
+
+func familyStats(trie *rstrie.RSTrie, bitLen int) FamilyStats {
+	stats := FamilyStats{
+		Entries:       0,
+		Addresses:     big.NewInt(0),
+		PrefixLengths: map[int]int{},
+	}
+
+	for bits := range trie.Each() {
+		stats.Entries++
+		stats.PrefixLengths[bits.Len()]++
+		stats.Addresses.Add(stats.Addresses, new(big.Int).Lsh(big.NewInt(1), uint(bitLen-bits.Len()))) //nolint: gosec
+	}
+
+	return stats
+}
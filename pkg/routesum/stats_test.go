@@ -0,0 +1,63 @@
+package routesum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	rs := NewRouteSum()
+	for _, s := range []string{
+		"192.0.2.0/25",
+		"192.0.2.128/25",
+		"192.0.2.7",
+		"198.51.100.1",
+		"2001:db8::/32",
+		"2001:db8::1",
+	} {
+		require.NoError(t, rs.InsertFromString(s))
+	}
+
+	assert.Equal(
+		t,
+		Stats{
+			Inputs:  6,
+			Entries: 3,
+			IPv4: FamilyStats{
+				Entries:       2,
+				Addresses:     big.NewInt(257),
+				PrefixLengths: map[int]int{24: 1, 32: 1},
+			},
+			IPv6: FamilyStats{
+				Entries:       1,
+				Addresses:     new(big.Int).Lsh(big.NewInt(1), 96),
+				PrefixLengths: map[int]int{32: 1},
+			},
+		},
+		rs.Stats(),
+		"got expected stats",
+	)
+
+	assert.Equal(
+		t,
+		Stats{
+			Inputs:  0,
+			Entries: 2,
+			IPv4: FamilyStats{
+				Entries:       1,
+				Addresses:     new(big.Int).Lsh(big.NewInt(1), 32),
+				PrefixLengths: map[int]int{0: 1},
+			},
+			IPv6: FamilyStats{
+				Entries:       1,
+				Addresses:     new(big.Int).Lsh(big.NewInt(1), 128),
+				PrefixLengths: map[int]int{0: 1},
+			},
+		},
+		NewRouteSum().Complement().Stats(),
+		"addresses are counted beyond 64 bits",
+	)
+}